@@ -0,0 +1,59 @@
+package reqstrategy
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrAmplificationExceeded is returned when the logical call would make more requests than allowed, see WithMaxRequests
+var ErrAmplificationExceeded = errors.New("request amplification limit exceeded")
+
+type amplification struct {
+	limit int32
+	sent  int32
+}
+
+// WithMaxRequests caps the number of requests the logical call may produce. The cap is shared by the requests
+// derived from the returned one, so if Race legs or nested strategies built on top of it would go over the cap,
+// they fail with ErrAmplificationExceeded. Strategies check the worst case upfront, e.g. Retry with 3 intervals
+// fails before the first attempt if less than 4 requests are left
+func WithMaxRequests(r *http.Request, n int) *http.Request {
+	return withValue(r, keyAmplification, &amplification{limit: int32(n)})
+}
+
+func (a *amplification) take() bool {
+	if atomic.AddInt32(&a.sent, 1) > a.limit {
+		atomic.AddInt32(&a.sent, -1)
+		return false
+	}
+	return true
+}
+
+func (a *amplification) remaining() int {
+	return int(a.limit - atomic.LoadInt32(&a.sent))
+}
+
+// takeRequest accounts the request about to be sent
+func takeRequest(r *http.Request) error {
+	if a, ok := r.Context().Value(keyAmplification).(*amplification); ok && !a.take() {
+		return ErrAmplificationExceeded
+	}
+	return nil
+}
+
+// planRequests checks whether every request may be sent up to n times without exceeding the caps
+func planRequests(requests []*http.Request, n int) error {
+	planned := make(map[*amplification]int)
+	for _, r := range requests {
+		if a, ok := r.Context().Value(keyAmplification).(*amplification); ok {
+			planned[a] += n
+		}
+	}
+	for a, n := range planned {
+		if a.remaining() < n {
+			return ErrAmplificationExceeded
+		}
+	}
+	return nil
+}
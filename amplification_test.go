@@ -0,0 +1,40 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_WithMaxRequests(t *testing.T) {
+	var count int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&count, 1)
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	req := WithMaxRequests(newRequest(t), 3)
+	if _, err := Retry(client, req, time.Millisecond, time.Millisecond, time.Millisecond); err != ErrAmplificationExceeded {
+		t.Fatalf("expected ErrAmplificationExceeded, got %v", err)
+	}
+	if count != 0 {
+		t.Fatalf("expected no requests to be sent, got %d", count)
+	}
+
+	if _, err := Some(client, WithTag(req, "a"), WithTag(req, "b")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := Some(client, WithTag(req, "a"), WithTag(req, "b")); err != ErrAmplificationExceeded {
+		t.Fatalf("expected ErrAmplificationExceeded, got %v", err)
+	}
+	if _, err := Do(client, req); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := Do(client, req); err != ErrAmplificationExceeded {
+		t.Fatalf("expected ErrAmplificationExceeded, got %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 requests to be sent, got %d", count)
+	}
+}
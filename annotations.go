@@ -0,0 +1,53 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"sync"
+)
+
+type annotations struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// Annotate attaches the key-value pair to the response, meant to be used by validators to pass
+// the metadata extracted during validation to the caller, e.g. Annotate(resp, "schema-version", "3").
+// Only responses received with Do or strategies can be annotated, for others it is a no-op
+func Annotate(resp *http.Response, key, value string) {
+	if a := annotationsOf(resp); a != nil {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.values[key] = value
+	}
+}
+
+// Annotations returns the copy of all the key-value pairs attached to the response with Annotate
+func Annotations(resp *http.Response) map[string]string {
+	values := make(map[string]string)
+	if a := annotationsOf(resp); a != nil {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		for k, v := range a.values {
+			values[k] = v
+		}
+	}
+	return values
+}
+
+// Annotation returns the value attached to the response with Annotate and whether it was there
+func Annotation(resp *http.Response, key string) (string, bool) {
+	value, ok := Annotations(resp)[key]
+	return value, ok
+}
+
+func withAnnotations(r *http.Request) *http.Request {
+	return withValue(r, keyAnnotations, &annotations{values: make(map[string]string)})
+}
+
+func annotationsOf(resp *http.Response) *annotations {
+	if resp == nil || resp.Request == nil {
+		return nil
+	}
+	a, _ := resp.Request.Context().Value(keyAnnotations).(*annotations)
+	return a
+}
@@ -0,0 +1,29 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_Annotate(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200, Header: http.Header{"X-Schema": {"3"}}}, nil
+	})
+
+	req := WithValidator(newRequest(t), func(resp *http.Response) error {
+		Annotate(resp, "schema-version", resp.Header.Get("X-Schema"))
+		return nil
+	})
+	resp, err := Do(client, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v, ok := Annotation(resp, "schema-version"); !ok || v != "3" {
+		t.Fatalf(`expected "schema-version" annotation to be "3", got "%s"`, v)
+	}
+
+	other, _ := Do(client, newRequest(t))
+	if len(Annotations(other)) != 0 {
+		t.Fatalf("expected annotations not to leak between responses, got %v", Annotations(other))
+	}
+}
@@ -0,0 +1,97 @@
+package reqstrategy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// AuditRecord describes a single attempt to send the request, see WithAudit
+type AuditRecord struct {
+	// Actor is who the request is made on behalf of
+	Actor    string
+	CallID   string
+	CallName string
+	Strategy string
+	Method   string
+	// URL has the password, if any, redacted
+	URL string
+	// Header holds the request headers with sensitive values redacted
+	Header   http.Header
+	Start    time.Time
+	Duration time.Duration
+	// Status is the response status code, zero if there was no response
+	Status int
+	// Err is the error the attempt failed with, empty on success
+	Err string
+	// Canceled tells the attempt was cancelled by the strategy, it is not a failure, see Attempt.Canceled
+	Canceled bool
+}
+
+// AuditSink receives the record of every attempt made for the audited requests, e.g. to log all
+// third-party API traffic for compliance. Record is called synchronously so it should not block
+type AuditSink interface {
+	Record(AuditRecord)
+}
+
+// WithAudit makes every attempt of the request recorded into the sink on behalf of actor. Values of the listed
+// headers are redacted in addition to Authorization, Proxy-Authorization, Cookie and Set-Cookie
+func WithAudit(r *http.Request, sink AuditSink, actor string, redact ...string) *http.Request {
+	redact = append(sensitiveHeaders[:len(sensitiveHeaders):len(sensitiveHeaders)], redact...)
+	return WithHook(r, func(a Attempt) {
+		record := AuditRecord{
+			Actor:    actor,
+			CallID:   a.CallID,
+			Method:   a.Request.Method,
+			URL:      redactURL(a.Request.URL),
+			Header:   redactHeader(a.Request.Header, redact),
+			Start:    a.Start,
+			Duration: a.Duration,
+		}
+		record.CallName, _ = a.Request.Context().Value(CallNameKey).(string)
+		record.Strategy, _ = a.Request.Context().Value(StrategyKey).(string)
+		if a.Response != nil {
+			record.Status = a.Response.StatusCode
+		}
+		if a.Err != nil && !a.Canceled {
+			record.Err = a.Err.Error()
+		}
+		record.Canceled = a.Canceled
+		sink.Record(record)
+	})
+}
+
+func redactURL(u *url.URL) string {
+	if u.User == nil {
+		return u.String()
+	}
+	redacted := *u
+	if _, ok := u.User.Password(); ok {
+		redacted.User = url.UserPassword(u.User.Username(), "xxxxx")
+	}
+	return redacted.String()
+}
+
+// MarshalJSON encodes the record as
+//
+//	{"actor": "billing", "call_id": "abc.1", "call_name": "charge", "strategy": "Retry", "method": "POST", "url": "...",
+//	 "header": {...}, "start": "...", "duration_ms": 12.5, "status": 200, "error": ""}
+//
+// attempts cancelled by the strategy have "canceled": true instead of the error
+func (r AuditRecord) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Actor    string      `json:"actor,omitempty"`
+		CallID   string      `json:"call_id,omitempty"`
+		CallName string      `json:"call_name,omitempty"`
+		Strategy string      `json:"strategy,omitempty"`
+		Method   string      `json:"method"`
+		URL      string      `json:"url"`
+		Header   http.Header `json:"header"`
+		Start    time.Time   `json:"start"`
+		Duration float64     `json:"duration_ms"`
+		Status   int         `json:"status,omitempty"`
+		Error    string      `json:"error,omitempty"`
+		Canceled bool        `json:"canceled,omitempty"`
+	}{r.Actor, r.CallID, r.CallName, r.Strategy, r.Method, r.URL, r.Header, r.Start, milliseconds(r.Duration), r.Status, r.Err, r.Canceled})
+}
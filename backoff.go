@@ -0,0 +1,99 @@
+package reqstrategy
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff tells how long to wait before the next attempt once the attempt number n failed, attempts are
+// counted from 1. Retrying stops when ok is false, built-in backoffs other than Intervals never stop, so
+// they are meant to be combined with the request context deadline or WithExpiry
+type Backoff interface {
+	Next(n int) (wait time.Duration, ok bool)
+}
+
+// BackoffFunc adapts the function to Backoff
+type BackoffFunc func(n int) (time.Duration, bool)
+
+// Next calls f
+func (f BackoffFunc) Next(n int) (time.Duration, bool) {
+	return f(n)
+}
+
+// Intervals is the Backoff waiting provided intervals one by one, the same as intervals of Retry
+func Intervals(intervals ...time.Duration) Backoff {
+	return BackoffFunc(func(n int) (time.Duration, bool) {
+		if n > len(intervals) {
+			return 0, false
+		}
+		return intervals[n-1], true
+	})
+}
+
+// Constant is the Backoff waiting the same interval between attempts
+func Constant(interval time.Duration) Backoff {
+	return BackoffFunc(func(int) (time.Duration, bool) {
+		return interval, true
+	})
+}
+
+// Linear is the Backoff waiting step longer after every attempt: step, 2*step, 3*step etc.
+func Linear(step time.Duration) Backoff {
+	return BackoffFunc(func(n int) (time.Duration, bool) {
+		return capped(float64(step)*float64(n), 0), true
+	})
+}
+
+// Exponential is the Backoff doubling the wait after every attempt starting from base, the wait is not
+// longer than max unless max is zero
+func Exponential(base, max time.Duration) Backoff {
+	return BackoffFunc(func(n int) (time.Duration, bool) {
+		return capped(float64(base)*math.Pow(2, float64(n-1)), max), true
+	})
+}
+
+// Fibonacci is the Backoff growing the wait along the Fibonacci sequence: base, base, 2*base, 3*base, 5*base etc.
+func Fibonacci(base time.Duration) Backoff {
+	return BackoffFunc(func(n int) (time.Duration, bool) {
+		a, b := 1.0, 1.0
+		for i := 1; i < n; i++ {
+			a, b = b, a+b
+		}
+		return capped(float64(base)*a, 0), true
+	})
+}
+
+// DecorrelatedJitter is the Backoff picking the random wait between base and three times the previous one,
+// not longer than max unless max is zero. It spreads retries of many clients better than the jittered
+// exponential backoff. Previous wait is forgotten on the first attempt, so the value is meant for one
+// retry loop at a time
+func DecorrelatedJitter(base, max time.Duration) Backoff {
+	var mu sync.Mutex
+	prev := base
+	return BackoffFunc(func(n int) (time.Duration, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if n == 1 {
+			prev = base
+		}
+		wait := float64(base)
+		if upper := 3 * float64(prev); upper > wait {
+			wait += rand.Float64() * (upper - wait)
+		}
+		prev = capped(wait, max)
+		return prev, true
+	})
+}
+
+// capped converts the wait to the duration not longer than max unless max is zero, guarding against overflow
+func capped(wait float64, max time.Duration) time.Duration {
+	if max > 0 && wait > float64(max) {
+		return max
+	}
+	if wait > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(wait)
+}
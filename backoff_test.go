@@ -0,0 +1,158 @@
+package reqstrategy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy/reqstrategytest"
+)
+
+func waits(b Backoff, n int) []time.Duration {
+	var waits []time.Duration
+	for i := 1; i <= n; i++ {
+		wait, ok := b.Next(i)
+		if !ok {
+			break
+		}
+		waits = append(waits, wait)
+	}
+	return waits
+}
+
+func Test_Backoff(t *testing.T) {
+	ms := time.Millisecond
+	cases := map[string]struct {
+		backoff  Backoff
+		expected []time.Duration
+	}{
+		"Intervals":   {Intervals(ms, 5*ms), []time.Duration{ms, 5 * ms}},
+		"Constant":    {Constant(ms), []time.Duration{ms, ms, ms, ms, ms}},
+		"Linear":      {Linear(ms), []time.Duration{ms, 2 * ms, 3 * ms, 4 * ms, 5 * ms}},
+		"Exponential": {Exponential(ms, 10*ms), []time.Duration{ms, 2 * ms, 4 * ms, 8 * ms, 10 * ms}},
+		"Fibonacci":   {Fibonacci(ms), []time.Duration{ms, ms, 2 * ms, 3 * ms, 5 * ms}},
+	}
+	for name, c := range cases {
+		if actual := waits(c.backoff, 5); fmt.Sprint(actual) != fmt.Sprint(c.expected) {
+			t.Errorf("%s: expected %v, got %v", name, c.expected, actual)
+		}
+	}
+
+	if wait, _ := Exponential(time.Second, 0).Next(1000); wait <= 0 {
+		t.Errorf("expected unbounded exponential backoff not to overflow, got %s", wait)
+	}
+}
+
+func Test_DecorrelatedJitter(t *testing.T) {
+	ms := time.Millisecond
+	b := DecorrelatedJitter(ms, 20*ms)
+	for round := 0; round < 10; round++ {
+		prev := ms
+		for i, wait := range waits(b, 10) {
+			if wait < ms || wait > 20*ms || wait > 3*prev {
+				t.Fatalf("attempt %d: expected wait between %s and %s, got %s", i+1, ms, 3*prev, wait)
+			}
+			prev = wait
+		}
+	}
+}
+
+func Test_RetryBackoff(t *testing.T) {
+	var calls int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		calls++
+		if calls < 4 {
+			return &http.Response{Request: r, StatusCode: 503}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	start := time.Now()
+	clock := reqstrategytest.NewVirtualClock(start)
+	req := WithClock(WithStatusRequired(newRequest(t), 200), clock)
+
+	resp, err := RetryBackoff(client, req, Exponential(time.Second, time.Minute))
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("expected success on the 4th attempt, got %v", err)
+	}
+	if elapsed := clock.Now().Sub(start); elapsed != 7*time.Second {
+		t.Fatalf("expected 1s+2s+4s of backoff, got %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls = 0
+	_, err = RetryBackoff(client, WithStatusRequired(newRequest(t).WithContext(ctx), 200), Constant(time.Hour))
+	if err == nil {
+		t.Fatalf("expected cancelled retries to stop")
+	}
+}
+
+func Test_RetryFunc(t *testing.T) {
+	var signatures []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		signatures = append(signatures, r.Header.Get("Signature"))
+		if len(signatures) < 3 {
+			return &http.Response{Request: r, StatusCode: 503}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	clock := reqstrategytest.NewVirtualClock(time.Now())
+	build := func(attempt int) (*http.Request, error) {
+		req := WithClock(WithStatusRequired(newRequest(t), 200), clock)
+		req.Header.Set("Signature", fmt.Sprintf("sig-%d", attempt))
+		return req, nil
+	}
+
+	resp, err := RetryFunc(client, build, Constant(time.Second))
+	if err != nil || resp.StatusCode != 200 {
+		t.Fatalf("expected success on the 3rd attempt, got %v", err)
+	}
+	if fmt.Sprint(signatures) != "[sig-1 sig-2 sig-3]" {
+		t.Fatalf("expected every attempt signed anew, got %v", signatures)
+	}
+
+	signatures = nil
+	_, err = RetryFunc(client, func(attempt int) (*http.Request, error) {
+		if attempt == 2 {
+			return nil, fmt.Errorf("no credentials")
+		}
+		return build(attempt)
+	}, Constant(time.Second))
+	if err == nil || err.Error() != "no credentials" || len(signatures) != 1 {
+		t.Fatalf("expected retries stopped by the build error, got %v after %d attempts", err, len(signatures))
+	}
+}
+
+func Test_Retry_WithMaxElapsed(t *testing.T) {
+	var calls int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{Request: r, StatusCode: 503}, nil
+	})
+	start := time.Now()
+	clock := reqstrategytest.NewVirtualClock(start)
+	req := WithClock(WithStatusRequired(newRequest(t), 200), clock)
+	req = WithMaxElapsed(WithBackoff(req, Exponential(time.Second, time.Minute)), 10*time.Second)
+
+	resp, err := Retry(client, req)
+	if err == nil || resp == nil || resp.StatusCode != 503 {
+		t.Fatalf("expected the last failed response, got %v", err)
+	}
+	if calls != 4 || clock.Now().Sub(start) != 7*time.Second {
+		t.Fatalf("expected 4 attempts within 10s, got %d after %s", calls, clock.Now().Sub(start))
+	}
+
+	calls = 0
+	req = WithMaxAttempts(WithBackoff(WithClock(WithStatusRequired(newRequest(t), 200), clock), Constant(time.Second)), 3)
+	if _, err := Retry(client, req); err == nil || calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+
+	calls = 0
+	req = WithMaxAttempts(WithClock(WithStatusRequired(newRequest(t), 200), clock), 2)
+	if _, err := Retry(client, req, time.Second, time.Second, time.Second); err == nil || calls != 2 {
+		t.Fatalf("expected 2 of 4 attempts, got %d", calls)
+	}
+}
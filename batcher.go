@@ -0,0 +1,144 @@
+package reqstrategy
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrBatcherClosed is the outcome of requests submitted to Batcher after Close
+	ErrBatcherClosed = errors.New("batcher is closed")
+	// ErrNoResponse is the outcome of the batched request the strategy returned no response for, e.g. failed within Some
+	ErrNoResponse = errors.New("no response")
+)
+
+// Batcher collects submitted requests into batches sent with the strategy, e.g. All or Some, once the batch
+// reaches size requests or maxWait passed since its first request was submitted. Outcomes are delivered
+// to per-request futures. It is safe for concurrent use
+//
+//	batcher := NewBatcher(client, Some, 50, 10*time.Millisecond)
+//	defer batcher.Close()
+//	resp, err := batcher.Submit(req).Wait()
+type Batcher struct {
+	client   *http.Client
+	strategy func(client *http.Client, requests ...*http.Request) ([]*http.Response, error)
+	size     int
+	maxWait  time.Duration
+
+	mu      sync.Mutex
+	wg      sync.WaitGroup
+	clock   Clock
+	pending []*Future
+	batch   int
+	closed  bool
+}
+
+// Future is the outcome of the request submitted to Batcher
+type Future struct {
+	done     chan struct{}
+	request  *http.Request
+	response *http.Response
+	err      error
+}
+
+// Done returns the channel closed once the outcome is known
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the batch the request belongs to is complete and returns the request outcome.
+// If the strategy failed as a whole every request of the batch gets its error, unless it is Errors
+// holding errors of every request
+func (f *Future) Wait() (*http.Response, error) {
+	<-f.done
+	return f.response, f.err
+}
+
+func (f *Future) resolve(resp *http.Response, err error) {
+	f.response, f.err = resp, err
+	close(f.done)
+}
+
+// NewBatcher creates Batcher sending batches of up to size requests with the strategy
+func NewBatcher(client *http.Client, strategy func(client *http.Client, requests ...*http.Request) ([]*http.Response, error), size int, maxWait time.Duration) *Batcher {
+	return &Batcher{client: client, strategy: strategy, size: size, maxWait: maxWait, clock: realClock{}}
+}
+
+// SetClock sets the time source driving the max wait of the batches started afterwards
+func (b *Batcher) SetClock(c Clock) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clock = c
+}
+
+// Submit adds the request to the current batch
+func (b *Batcher) Submit(r *http.Request) *Future {
+	f := &Future{done: make(chan struct{}), request: r}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		f.resolve(nil, ErrBatcherClosed)
+		return f
+	}
+
+	b.pending = append(b.pending, f)
+	if len(b.pending) >= b.size {
+		b.flush()
+	} else if len(b.pending) == 1 {
+		batch, timer := b.batch, b.clock.After(b.maxWait)
+		go func() {
+			<-timer
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.batch == batch {
+				b.flush()
+			}
+		}()
+	}
+	return f
+}
+
+// Close sends the requests submitted so far and waits for all batches to complete,
+// requests submitted afterwards fail with ErrBatcherClosed
+func (b *Batcher) Close() {
+	b.mu.Lock()
+	b.closed = true
+	b.flush()
+	b.mu.Unlock()
+	b.wg.Wait()
+}
+
+// flush sends the pending batch, it must be called with the lock held
+func (b *Batcher) flush() {
+	futures := b.pending
+	b.pending = nil
+	b.batch++
+	if len(futures) == 0 {
+		return
+	}
+
+	b.wg.Add(1)
+	spawn(func() {
+		defer b.wg.Done()
+		requests := make([]*http.Request, len(futures))
+		for i, f := range futures {
+			requests[i] = f.request
+		}
+		responses, err := b.strategy(b.client, requests...)
+		errs, _ := err.(Errors)
+		for i, f := range futures {
+			switch {
+			case len(errs) == len(futures):
+				f.resolve(nil, errs[i])
+			case err != nil:
+				f.resolve(nil, err)
+			case i >= len(responses) || responses[i] == nil:
+				f.resolve(nil, ErrNoResponse)
+			default:
+				f.resolve(responses[i], nil)
+			}
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Batcher(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/fail" {
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	var mu sync.Mutex
+	var batches []int
+	some := func(client *http.Client, requests ...*http.Request) ([]*http.Response, error) {
+		mu.Lock()
+		batches = append(batches, len(requests))
+		mu.Unlock()
+		return Some(client, requests...)
+	}
+
+	batcher := NewBatcher(client, some, 2, 20*time.Millisecond)
+	a := batcher.Submit(WithStatusRequired(newRequest(t, "a"), 200))
+	fail := batcher.Submit(WithStatusRequired(newRequest(t, "fail"), 200))
+	c := batcher.Submit(WithStatusRequired(newRequest(t, "c"), 200))
+
+	if resp, err := a.Wait(); err != nil || resp.Request.URL.Path != "/a" {
+		t.Fatalf("expected response of a, got %v", err)
+	}
+	if _, err := fail.Wait(); err != ErrNoResponse {
+		t.Fatalf("expected ErrNoResponse, got %v", err)
+	}
+	select {
+	case <-c.Done():
+		t.Fatal("expected c to wait for the timer")
+	default:
+	}
+	if resp, err := c.Wait(); err != nil || resp.Request.URL.Path != "/c" {
+		t.Fatalf("expected response of c, got %v", err)
+	}
+
+	d := batcher.Submit(WithStatusRequired(newRequest(t, "fail"), 200))
+	batcher.Close()
+	if _, err := d.Wait(); err == nil || err == ErrNoResponse {
+		t.Fatalf("expected the error of the only failed request, got %v", err)
+	}
+	if _, err := batcher.Submit(newRequest(t, "e")).Wait(); err != ErrBatcherClosed {
+		t.Fatalf("expected ErrBatcherClosed, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(batches) != 3 || batches[0] != 2 || batches[1] != 1 || batches[2] != 1 {
+		t.Fatalf("expected batches of 2, 1 and 1 requests, got %v", batches)
+	}
+}
+
+// manualClock fires the timers once told to
+type manualClock struct {
+	fire chan time.Time
+}
+
+func (c manualClock) Now() time.Time {
+	return time.Now()
+}
+
+func (c manualClock) After(d time.Duration) <-chan time.Time {
+	return c.fire
+}
+
+func Test_Batcher_SetClock(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	clock := manualClock{make(chan time.Time)}
+	batcher := NewBatcher(client, All, 10, time.Millisecond)
+	batcher.SetClock(clock)
+	defer batcher.Close()
+
+	f := batcher.Submit(newRequest(t))
+	select {
+	case <-f.Done():
+		t.Fatal("expected the batch to wait for the clock")
+	case <-time.After(20 * time.Millisecond):
+	}
+	clock.fire <- time.Now()
+	if _, err := f.Wait(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,136 @@
+package reqstrategy
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned by Do when sending the request would go over one of its budgets
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Budget caps the amount of body bytes sent and received within a time window. Once the limit is reached
+// requests fail fast with ErrBudgetExceeded until the next window starts. Budget is safe for concurrent use,
+// sharing one Budget across requests enforces global cap, separate Budgets per tag cap individual features
+type Budget struct {
+	limit  int64
+	window time.Duration
+
+	mu    sync.Mutex
+	clock Clock
+	start time.Time
+	spent int64
+}
+
+// NewBudget creates a Budget allowing to transfer limit bytes per window
+func NewBudget(limit int64, window time.Duration) *Budget {
+	return &Budget{limit: limit, window: window, clock: realClock{}}
+}
+
+// SetClock sets the time source windows are started with, requests with their own one use it instead, see WithClock
+func (b *Budget) SetClock(c Clock) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clock = c
+}
+
+// WithBudget makes the request accounted in provided Budget, can be used multiple times to apply several budgets
+func WithBudget(r *http.Request, b *Budget) *http.Request {
+	budgets, _ := r.Context().Value(keyBudgets).([]*Budget)
+	return withValue(r, keyBudgets, append(budgets[:len(budgets):len(budgets)], b))
+}
+
+// Remaining returns the amount of bytes left in the current window
+func (b *Budget) Remaining() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(b.clock.Now())
+	if b.spent > b.limit {
+		return 0
+	}
+	return b.limit - b.spent
+}
+
+// now returns the current time of the request clock, or of the budget one if the request has none
+func (b *Budget) now(r *http.Request) time.Time {
+	if c, ok := r.Context().Value(keyClock).(Clock); ok {
+		return c.Now()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.clock.Now()
+}
+
+// reserve accounts n bytes if they fit into the budget, returning the start of the window they are accounted in
+func (b *Budget) reserve(now time.Time, n int64) (time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now)
+	if b.spent >= b.limit || b.spent+n > b.limit {
+		return time.Time{}, false
+	}
+	b.spent += n
+	return b.start, true
+}
+
+// release gives back n bytes reserved in the window started at start, nothing is given back once it is over
+func (b *Budget) release(start time.Time, n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.start.Equal(start) {
+		return
+	}
+	if b.spent -= n; b.spent < 0 {
+		b.spent = 0
+	}
+}
+
+func (b *Budget) spend(now time.Time, n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now)
+	b.spent += n
+}
+
+// roll starts a new window if the current one is over, must be called holding the lock
+func (b *Budget) roll(now time.Time) {
+	if now.Sub(b.start) >= b.window {
+		b.start = now
+		b.spent = 0
+	}
+}
+
+// reserveBudgets checks the request against its budgets accounting the request body,
+// nothing is accounted if any of the budgets is exceeded
+func reserveBudgets(r *http.Request) error {
+	budgets, _ := r.Context().Value(keyBudgets).([]*Budget)
+	sent := r.ContentLength
+	if sent < 0 {
+		sent = 0
+	}
+	windows := make([]time.Time, len(budgets))
+	for i, b := range budgets {
+		var ok bool
+		if windows[i], ok = b.reserve(b.now(r), sent); !ok {
+			for j, b := range budgets[:i] {
+				b.release(windows[j], sent)
+			}
+			return ErrBudgetExceeded
+		}
+	}
+	return nil
+}
+
+// spendBudgets makes response body reads accounted in the request budgets
+func spendBudgets(r *http.Request, resp *http.Response) {
+	budgets, _ := r.Context().Value(keyBudgets).([]*Budget)
+	if len(budgets) == 0 || resp == nil || resp.Body == nil {
+		return
+	}
+	resp.Body = &countingBody{resp.Body, func(n int) {
+		for _, b := range budgets {
+			b.spend(b.now(r), int64(n))
+		}
+	}}
+}
@@ -0,0 +1,67 @@
+package reqstrategy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy/reqstrategytest"
+)
+
+func Test_WithBudget(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("0123456789"))}, nil
+	})
+
+	budget := NewBudget(15, time.Hour)
+	for i, want := range []error{nil, nil, ErrBudgetExceeded} {
+		resp, err := Do(client, WithBudget(newRequest(t), budget))
+		if err != want {
+			t.Fatalf("request #%d: expected %v error, got %v", i, want, err)
+		}
+		if resp != nil {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+	}
+	if remaining := budget.Remaining(); remaining != 0 {
+		t.Fatalf("expected 0 bytes remaining, got %d", remaining)
+	}
+}
+
+func Test_WithBudget_rollback(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	clock := reqstrategytest.NewVirtualClock(time.Now())
+	large, small := NewBudget(100, time.Minute), NewBudget(5, time.Minute)
+	large.SetClock(clock)
+	small.SetClock(clock)
+
+	req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader("0123456789"))
+	if _, err := Do(client, WithBudget(WithBudget(req, large), small)); err != ErrBudgetExceeded {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if remaining := large.Remaining(); remaining != 100 {
+		t.Fatalf("expected the reservation given back, got %d bytes remaining", remaining)
+	}
+
+	start, _ := large.reserve(clock.Now(), 10)
+	clock.After(time.Minute)
+	large.spend(clock.Now(), 1)
+	large.release(start, 10)
+	if remaining := large.Remaining(); remaining != 99 {
+		t.Fatalf("expected the reservation of the previous window not given back, got %d bytes remaining", remaining)
+	}
+
+	small.spend(clock.Now(), 5)
+	if _, err := Do(client, WithClock(WithBudget(newRequest(t), small), clock)); err != ErrBudgetExceeded {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	clock.After(time.Minute)
+	if _, err := Do(client, WithClock(WithBudget(newRequest(t), small), clock)); err != nil {
+		t.Fatalf("expected the budget renewed by the request clock, got %v", err)
+	}
+}
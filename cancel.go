@@ -0,0 +1,89 @@
+package reqstrategy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// CancelCause tells why the strategy was stopped before completing all of its steps
+type CancelCause int
+
+const (
+	// CauseCaller means the caller aborted the strategy through the request context
+	CauseCaller CancelCause = iota + 1
+	// CauseBudget means the request budget or amplification limit was exhausted, see WithBudget and WithMaxRequests
+	CauseBudget
+	// CauseKillSwitch means the request was turned off, see WithKillSwitch
+	CauseKillSwitch
+)
+
+func (c CancelCause) String() string {
+	switch c {
+	case CauseCaller:
+		return "caller aborted"
+	case CauseBudget:
+		return "budget exceeded"
+	case CauseKillSwitch:
+		return "kill switch"
+	}
+	return "unknown"
+}
+
+// CancelError is returned by strategies iterating over many steps, like Upload chunks, Chain stages or
+// Waterfall steps, when they were stopped at a cancellation checkpoint between the steps or the step
+// failed for the same reason
+type CancelError struct {
+	Cause CancelCause
+	// Step is the index of the step which was not completed
+	Step int
+	Err  error
+}
+
+func (e *CancelError) Error() string {
+	return fmt.Sprintf("cancelled at step %d, %s: %s", e.Step, e.Cause, e.Err)
+}
+
+func (e *CancelError) Unwrap() error {
+	return e.Err
+}
+
+// checkpoint reports whether the strategy may proceed with the step of the request, it returns *CancelError
+// if the request context is done, the kill switch turned the request off or its budget is exhausted
+func checkpoint(r *http.Request, step int) error {
+	err := r.Context().Err()
+	if err == nil {
+		err = allowed(r)
+	}
+	if err == nil {
+		budgets, _ := r.Context().Value(keyBudgets).([]*Budget)
+		for _, b := range budgets {
+			if b.Remaining() == 0 {
+				err = ErrBudgetExceeded
+				break
+			}
+		}
+	}
+	if err == nil {
+		return nil
+	}
+	return interrupted(r, step, err)
+}
+
+// interrupted wraps the error the step failed with into *CancelError if it has a cancellation cause
+func interrupted(r *http.Request, step int, err error) error {
+	var cause CancelCause
+	switch {
+	case err == nil:
+		return nil
+	case r.Context().Err() != nil:
+		cause = CauseCaller
+	case errors.Is(err, ErrBudgetExceeded), errors.Is(err, ErrAmplificationExceeded):
+		cause = CauseBudget
+	case errors.Is(err, ErrDisabled):
+		cause = CauseKillSwitch
+	default:
+		return err
+	}
+	return &CancelError{Cause: cause, Step: step, Err: err}
+}
@@ -0,0 +1,84 @@
+package reqstrategy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_CancelError_caller(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var chunks int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		chunks++
+		cancel()
+		return &http.Response{Request: r, StatusCode: StatusResumeIncomplete, Body: http.NoBody}, nil
+	})
+
+	req, _ := http.NewRequestWithContext(ctx, "PUT", "http://localhost/upload", nil)
+	_, err := Upload(client, req, strings.NewReader("0123456789"), 5, time.Millisecond)
+	var cancelErr *CancelError
+	if !errors.As(err, &cancelErr) {
+		t.Fatalf("expected *CancelError, got %v", err)
+	}
+	if cancelErr.Cause != CauseCaller || cancelErr.Step != 1 || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected caller abort at step 1, got %s", err)
+	}
+	if chunks != 1 {
+		t.Fatalf("expected 1 chunk sent, got %d", chunks)
+	}
+}
+
+func Test_CancelError_killSwitch(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200, Body: http.NoBody}, nil
+	})
+	kill := NewKillSwitch()
+
+	_, err := Chain(client,
+		func(*http.Response) (*http.Request, error) {
+			return WithKillSwitch(newRequest(t, "first"), kill), nil
+		},
+		func(*http.Response) (*http.Request, error) {
+			kill.DisableHost("localhost")
+			return WithKillSwitch(newRequest(t, "second"), kill), nil
+		},
+	)
+	var cancelErr *CancelError
+	if !errors.As(err, &cancelErr) {
+		t.Fatalf("expected *CancelError, got %v", err)
+	}
+	if cancelErr.Cause != CauseKillSwitch || cancelErr.Step != 1 || !errors.Is(err, ErrDisabled) {
+		t.Fatalf("expected kill switch at step 1, got %s", err)
+	}
+}
+
+func Test_CancelError_budget(t *testing.T) {
+	var sent int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		sent++
+		return &http.Response{Request: r, StatusCode: 200, Body: http.NoBody}, nil
+	})
+	budget := NewBudget(4, time.Hour)
+	step := func(path string) Step {
+		r, _ := http.NewRequest("POST", "http://localhost/"+path, strings.NewReader("data"))
+		return Step{Request: WithBudget(r, budget), Name: path}
+	}
+
+	_, err := Waterfall(client, []Step{step("first"), step("second"), step("third")})
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) || stepErr.Step != 1 {
+		t.Fatalf("expected *StepError at step 1, got %v", err)
+	}
+	var cancelErr *CancelError
+	if !errors.As(err, &cancelErr) || cancelErr.Cause != CauseBudget || !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected budget exhaustion, got %s", err)
+	}
+	if sent != 1 {
+		t.Fatalf("expected 1 request sent, got %d", sent)
+	}
+}
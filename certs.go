@@ -0,0 +1,70 @@
+package reqstrategy
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+)
+
+// CertificateProvider returns the client certificate to present. It is called on every attempt and
+// TLS handshake so it should be cheap, e.g. return the certificate reloaded from disk in background.
+// Returning the same pointer means the certificate has not changed
+type CertificateProvider func() (*tls.Certificate, error)
+
+// RotatingTransport returns a transport presenting the client certificate returned by provider.
+// Once provider returns a new certificate, idle connections authenticated with the previous one are
+// closed, so long-running clients (Runner, Queue etc) pick up rotated certificates without rebuilding.
+// Base transport is not modified, http.DefaultTransport is used if base is <nil>
+func RotatingTransport(base *http.Transport, provider CertificateProvider) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	t := &rotatingTransport{transport: base.Clone(), provider: provider}
+	cfg := &tls.Config{}
+	if t.transport.TLSClientConfig != nil {
+		cfg = t.transport.TLSClientConfig.Clone()
+	}
+	cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return t.certificate()
+	}
+	t.transport.TLSClientConfig = cfg
+	return t
+}
+
+type rotatingTransport struct {
+	transport *http.Transport
+	provider  CertificateProvider
+
+	mu      sync.Mutex
+	current *tls.Certificate
+}
+
+func (t *rotatingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.URL.Scheme == "https" {
+		if _, err := t.certificate(); err != nil {
+			return nil, err
+		}
+	}
+	return t.transport.RoundTrip(r)
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the underlying transport
+func (t *rotatingTransport) CloseIdleConnections() {
+	t.transport.CloseIdleConnections()
+}
+
+// certificate asks the provider for the certificate dropping idle connections if it was rotated
+func (t *rotatingTransport) certificate() (*tls.Certificate, error) {
+	cert, err := t.provider()
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	rotated := t.current != nil && t.current != cert
+	t.current = cert
+	t.mu.Unlock()
+	if rotated {
+		t.transport.CloseIdleConnections()
+	}
+	return cert, nil
+}
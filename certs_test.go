@@ -0,0 +1,77 @@
+package reqstrategy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newCertificate(t *testing.T, serial int64) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func Test_RotatingTransport(t *testing.T) {
+	var mu sync.Mutex
+	var serials []int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		serials = append(serials, r.TLS.PeerCertificates[0].SerialNumber.Int64())
+		mu.Unlock()
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	current := newCertificate(t, 1)
+	provider := func() (*tls.Certificate, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return current, nil
+	}
+	client := &http.Client{Transport: RotatingTransport(server.Client().Transport.(*http.Transport), provider)}
+
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			rotated := newCertificate(t, 2)
+			mu.Lock()
+			current = rotated
+			mu.Unlock()
+		}
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := Do(client, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		resp.Body.Close()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(serials) != 3 || serials[0] != 1 || serials[1] != 1 || serials[2] != 2 {
+		t.Fatalf("expected certificates 1, 1, 2 to be presented, got %v", serials)
+	}
+}
@@ -0,0 +1,36 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"time"
+)
+
+// Clock is the source of time used by strategies waiting between attempts
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// WithClock replaces the time source used by strategies for the request. Together with building with
+// reqstrategy_det tag, which makes strategies run requests one by one in the caller's goroutine,
+// virtual clocks allow reproducible tests of strategies, see reqstrategytest.VirtualClock
+func WithClock(r *http.Request, c Clock) *http.Request {
+	return withValue(r, keyClock, c)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func clockOf(r *http.Request) Clock {
+	if c, ok := r.Context().Value(keyClock).(Clock); ok {
+		return c
+	}
+	return realClock{}
+}
@@ -0,0 +1,25 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy/reqstrategytest"
+)
+
+func Test_WithClock(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 500}, nil
+	})
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := reqstrategytest.NewVirtualClock(start)
+	req := WithClock(WithStatusRequired(newRequest(t), 200), clock)
+	if _, err := Retry(client, req, time.Hour, 2*time.Hour); err == nil {
+		t.Fatal("expected error")
+	}
+	if elapsed := clock.Now().Sub(start); elapsed != 3*time.Hour {
+		t.Fatalf("expected 3h of virtual time to pass, got %s", elapsed)
+	}
+}
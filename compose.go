@@ -0,0 +1,296 @@
+package reqstrategy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Strategy executes requests in a particular manner. Unlike strategy functions, strategies are values
+// which can be configured once, passed around and combined, see Compose
+type Strategy interface {
+	Execute(ctx context.Context, client *http.Client, requests ...*http.Request) ([]*http.Response, error)
+}
+
+// StrategyFunc adapts the function to Strategy
+type StrategyFunc func(ctx context.Context, client *http.Client, requests ...*http.Request) ([]*http.Response, error)
+
+// Execute calls f
+func (f StrategyFunc) Execute(ctx context.Context, client *http.Client, requests ...*http.Request) ([]*http.Response, error) {
+	return f(ctx, client, requests...)
+}
+
+// composable is the built-in Strategy, inner strategy sends what it would send with Do otherwise
+type composable struct {
+	execute func(client *http.Client, requests []*http.Request, inner Strategy) ([]*http.Response, error)
+	inner   Strategy
+}
+
+func (s composable) Execute(ctx context.Context, client *http.Client, requests ...*http.Request) ([]*http.Response, error) {
+	requests, release := bind(ctx, requests)
+	responses, err := s.execute(client, requests, s.inner)
+	if err != nil {
+		release()
+		return responses, err
+	}
+	releaseOnClose(responses, release)
+	return responses, nil
+}
+
+// Compose returns the strategy running inner for everything outer sends: every attempt of RetryStrategy,
+// every request of RaceStrategy, AllStrategy and FallbackStrategy. E.g. Compose(RetryStrategy(time.Second), RaceStrategy())
+// retries the whole race while Compose(RaceStrategy(), RetryStrategy(time.Second)) races the requests retried
+// independently. Compose(a, Compose(b, c)) and Compose(Compose(a, b), c) are the same. Only built-in strategies
+// can be the outer one, the composed strategy fails otherwise
+func Compose(outer, inner Strategy) Strategy {
+	s, ok := outer.(composable)
+	if !ok {
+		return StrategyFunc(func(context.Context, *http.Client, ...*http.Request) ([]*http.Response, error) {
+			return nil, fmt.Errorf("strategy %T can not be composed", outer)
+		})
+	}
+	if s.inner != nil {
+		inner = Compose(s.inner, inner)
+	}
+	s.inner = inner
+	return s
+}
+
+// RetryStrategy is the Strategy of Retry, every attempt sends all requests with All unless composed.
+// Failed attempt responses are closed
+func RetryStrategy(intervals ...time.Duration) Strategy {
+	return composable{execute: func(client *http.Client, requests []*http.Request, inner Strategy) ([]*http.Response, error) {
+		if inner == nil && len(requests) == 1 {
+			return single(Retry(client, requests[0], intervals...))
+		}
+		if len(requests) == 0 {
+			return nil, nil
+		}
+		requests, err := rewindables(withStrategies(requests, "Retry"))
+		if err != nil {
+			return nil, err
+		}
+		intervals := intervals
+		for _, r := range requests {
+			if noRetry(r) {
+				intervals = nil
+			}
+		}
+		if err := planRequests(requests, len(intervals)+1); err != nil {
+			return nil, err
+		}
+		var responses []*http.Response
+		_, err = retry(requests[0], intervals, func(attempt int) (*http.Response, error) {
+			for _, resp := range responses {
+				closeBody(resp)
+			}
+			attempted := make([]*http.Request, len(requests))
+			for i, r := range requests {
+				r, err := rewind(r, attempt)
+				if err != nil {
+					return nil, err
+				}
+				attempted[i] = withAttempt(r, attempt)
+			}
+			var err error
+			if inner == nil {
+				responses, err = All(client, attempted...)
+			} else {
+				responses, err = inner.Execute(context.Background(), client, attempted...)
+			}
+			return nil, err
+		})
+		if err != nil {
+			for _, resp := range responses {
+				closeBody(resp)
+			}
+			return nil, err
+		}
+		return responses, nil
+	}}
+}
+
+// RaceStrategy is the Strategy of Race, it returns the single response of the winner
+func RaceStrategy() Strategy {
+	return composable{execute: func(client *http.Client, requests []*http.Request, inner Strategy) ([]*http.Response, error) {
+		if inner == nil {
+			resp, err := Race(client, requests...)
+			return single(resp, err)
+		}
+		requests = withStrategies(requests, "Race")
+		results, cancels := runLegs(client, requests, inner)
+		errs := make(Errors, len(requests))
+		for received := 1; received <= len(requests); received++ {
+			res := <-results
+			if res.err != nil {
+				cancels[res.order]()
+				errs[res.order] = res.err
+				continue
+			}
+			for i, cancel := range cancels {
+				if i != res.order {
+					cancel()
+				}
+			}
+			cancelOnClose(res.response, cancels[res.order])
+			go func(n int) {
+				for ; n > 0; n-- {
+					closeBody((<-results).response)
+				}
+			}(len(requests) - received)
+			return single(res.response, nil)
+		}
+		return nil, errs
+	}}
+}
+
+// AllStrategy is the Strategy of All
+func AllStrategy() Strategy {
+	return composable{execute: func(client *http.Client, requests []*http.Request, inner Strategy) ([]*http.Response, error) {
+		if inner == nil {
+			return All(client, requests...)
+		}
+		requests = withStrategies(requests, "All")
+		results, cancels := runLegs(client, requests, inner)
+		responses := make([]*http.Response, len(requests))
+		var err error
+		for range requests {
+			res := <-results
+			if res.err != nil && err == nil {
+				err = res.err
+				for _, cancel := range cancels {
+					cancel()
+				}
+			}
+			responses[res.order] = res.response
+		}
+		for i, resp := range responses {
+			if err != nil {
+				closeBody(resp)
+			} else {
+				cancelOnClose(resp, cancels[i])
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return responses, nil
+	}}
+}
+
+// FallbackStrategy is the Strategy of Fallback, it returns the single response of the request which succeeded
+func FallbackStrategy() Strategy {
+	return composable{execute: func(client *http.Client, requests []*http.Request, inner Strategy) ([]*http.Response, error) {
+		if inner == nil {
+			resp, err := Fallback(client, requests...)
+			return single(resp, err)
+		}
+		var err error
+		for i, r := range requests {
+			if err := checkpoint(r, i); err != nil {
+				return nil, err
+			}
+			var resp *http.Response
+			resp, err = leg(client, withChildID(withStrategy(r, "Fallback"), i+1), inner)
+			if err == nil {
+				return single(resp, nil)
+			}
+			closeBody(resp)
+			err = interrupted(r, i, err)
+		}
+		return nil, err
+	}}
+}
+
+// single returns the response of the strategy returning one response as the list
+func single(resp *http.Response, err error) ([]*http.Response, error) {
+	if resp == nil {
+		return nil, err
+	}
+	return []*http.Response{resp}, err
+}
+
+// runLegs sends every request with the inner strategy simultaneously, each request is cancelled with its own function.
+// Attempts cancelled with it are reported as Attempt.Canceled
+func runLegs(client *http.Client, requests []*http.Request, inner Strategy) (<-chan result, []context.CancelFunc) {
+	results := make(chan result, len(requests))
+	cancels := make([]context.CancelFunc, len(requests))
+	for i, r := range requests {
+		ctx, cancel := context.WithCancel(r.Context())
+		stopped := new(int32)
+		cancels[i] = func() {
+			atomic.StoreInt32(stopped, 1)
+			cancel()
+		}
+		i, r := i, withStopped(withChildID(r.WithContext(ctx), i+1), stopped)
+		spawn(func() {
+			resp, err := leg(client, r, inner)
+			results <- result{i, resp, err}
+		})
+	}
+	return results, cancels
+}
+
+// leg sends the request with the inner strategy, the first response is returned, others are closed
+func leg(client *http.Client, r *http.Request, inner Strategy) (*http.Response, error) {
+	responses, err := inner.Execute(context.Background(), client, r)
+	if len(responses) == 0 {
+		if err == nil {
+			err = ErrNoResponse
+		}
+		return nil, err
+	}
+	for _, resp := range responses[1:] {
+		closeBody(resp)
+	}
+	return responses[0], err
+}
+
+// bind makes requests cancelled with ctx until release is called, ctx which is never done is ignored
+func bind(ctx context.Context, requests []*http.Request) ([]*http.Request, func()) {
+	if ctx == nil || ctx.Done() == nil {
+		return requests, func() {}
+	}
+	bound := make([]*http.Request, len(requests))
+	cancels := make([]context.CancelFunc, len(requests))
+	for i, r := range requests {
+		rctx, cancel := context.WithCancel(r.Context())
+		if ctx.Err() != nil {
+			cancel()
+		}
+		go func() {
+			select {
+			case <-ctx.Done():
+				cancel()
+			case <-rctx.Done():
+			}
+		}()
+		bound[i], cancels[i] = r.WithContext(rctx), cancel
+	}
+	return bound, func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
+
+// releaseOnClose calls release once bodies of all responses are closed
+func releaseOnClose(responses []*http.Response, release func()) {
+	pending := int32(1)
+	done := func() {
+		if atomic.AddInt32(&pending, -1) == 0 {
+			release()
+		}
+	}
+	for _, resp := range responses {
+		if resp != nil && resp.Body != nil {
+			atomic.AddInt32(&pending, 1)
+			var once sync.Once
+			cancelOnClose(resp, func() { once.Do(done) })
+		}
+	}
+	done()
+}
@@ -0,0 +1,94 @@
+package reqstrategy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyClient fails the first request to "/a" and all requests to other paths with 503, counting requests by path
+func flakyClient() (*http.Client, func(path string) int) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[r.URL.Path]++
+		if r.URL.Path == "/a" && calls["/a"] > 1 {
+			return &http.Response{Request: r, StatusCode: 200, Body: http.NoBody}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 503, Body: http.NoBody}, nil
+	})
+	return client, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[path]
+	}
+}
+
+func Test_Compose(t *testing.T) {
+	a := WithStatusRequired(newRequest(t, "a"), 200)
+	b := WithStatusRequired(newRequest(t, "b"), 200)
+
+	// every request is retried independently within the race
+	client, _ := flakyClient()
+	resps, err := Compose(RaceStrategy(), RetryStrategy(time.Millisecond, time.Millisecond)).Execute(context.Background(), client, a, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(resps) != 1 || resps[0].Request.URL.Path != "/a" {
+		t.Fatalf(`expected the single response of "/a", got %d`, len(resps))
+	}
+	resps[0].Body.Close()
+
+	// the whole race is retried
+	client, calls := flakyClient()
+	resps, err = Compose(RetryStrategy(time.Millisecond), RaceStrategy()).Execute(context.Background(), client, a, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resps[0].Body.Close()
+	if calls("/a") != 2 {
+		t.Fatalf(`expected 2 races, got %d requests to "/a"`, calls("/a"))
+	}
+
+	client, calls = flakyClient()
+	_, err = Compose(AllStrategy(), Compose(RetryStrategy(time.Millisecond), FallbackStrategy())).Execute(context.Background(), client, b)
+	if err == nil || calls("/b") != 2 {
+		t.Fatalf("expected failure after 2 attempts, got %d", calls("/b"))
+	}
+}
+
+func Test_Compose_custom(t *testing.T) {
+	custom := StrategyFunc(func(ctx context.Context, client *http.Client, requests ...*http.Request) ([]*http.Response, error) {
+		return All(client, requests...)
+	})
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	if _, err := Compose(RaceStrategy(), custom).Execute(context.Background(), client, newRequest(t)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := Compose(custom, RaceStrategy()).Execute(context.Background(), client, newRequest(t)); err == nil {
+		t.Fatalf("expected custom outer strategy to fail")
+	}
+}
+
+func Test_Strategy_cancelled(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if err := r.Context().Err(); err != nil {
+			return nil, err
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Compose(RetryStrategy(time.Millisecond), AllStrategy()).Execute(ctx, client, newRequest(t))
+	if err == nil {
+		t.Fatalf("expected cancelled execution to fail")
+	}
+}
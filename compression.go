@@ -0,0 +1,93 @@
+package reqstrategy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// Compression gzips request bodies exceeding the size threshold. Hosts responding with
+// 415 Unsupported Media Type to the compressed request are disabled and the request is repeated
+// uncompressed. Compression is safe for concurrent use and is meant to be shared across requests
+type Compression struct {
+	threshold int64
+
+	mu       sync.Mutex
+	disabled map[string]bool
+}
+
+// NewCompression creates Compression for bodies of threshold bytes or larger, except for listed hosts
+func NewCompression(threshold int64, disabledHosts ...string) *Compression {
+	c := &Compression{threshold: threshold, disabled: make(map[string]bool)}
+	for _, host := range disabledHosts {
+		c.disabled[host] = true
+	}
+	return c
+}
+
+// WithCompression makes Do compress the request body according to provided Compression. Body is read
+// through GetBody when set, so retried requests are compressed again from the original body
+func WithCompression(r *http.Request, c *Compression) *http.Request {
+	return withValue(r, keyCompression, c)
+}
+
+// Disable turns compression off for the host
+func (c *Compression) Disable(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disabled[host] = true
+}
+
+// Enabled reports whether request bodies are compressed for the host
+func (c *Compression) Enabled(host string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.disabled[host]
+}
+
+// compress returns the request with the gzipped body and the same request with the original body
+// to fall back to. Request is returned as is when no compression is needed
+func compress(r *http.Request) (*http.Request, *http.Request, error) {
+	c, ok := r.Context().Value(keyCompression).(*Compression)
+	if !ok || r.Body == nil || r.Body == http.NoBody || r.Header.Get("Content-Encoding") != "" {
+		return r, nil, nil
+	}
+	if !c.Enabled(r.URL.Host) || (r.ContentLength > 0 && r.ContentLength < c.threshold) {
+		return r, nil, nil
+	}
+
+	data, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	plain := withBody(r, data)
+	if int64(len(data)) < c.threshold {
+		return plain, nil, nil
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, nil, err
+	}
+	compressed := withHeader(withBody(r, buf.Bytes()), "Content-Encoding", "gzip")
+	return compressed, plain, nil
+}
+
+// withBody returns a copy of the request with the body replaced
+func withBody(r *http.Request, data []byte) *http.Request {
+	r = r.WithContext(r.Context())
+	r.ContentLength = int64(len(data))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+	r.Body, _ = r.GetBody()
+	return r
+}
@@ -0,0 +1,64 @@
+package reqstrategy
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_WithCompression(t *testing.T) {
+	var bodies []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			if r.URL.Host == "legacy" {
+				return &http.Response{Request: r, StatusCode: 415}, nil
+			}
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			r.Body = zr
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, r.Header.Get("Content-Encoding")+":"+string(body))
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	compression := NewCompression(10)
+	for _, url := range []string{"http://localhost/", "http://localhost/", "http://legacy/", "http://legacy/"} {
+		for _, body := range []string{"short", "long enough body"} {
+			req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+			if _, err := Do(client, WithStatusRequired(WithCompression(req, compression), 200)); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+	}
+
+	want := ":short,gzip:long enough body,:short,gzip:long enough body,:short,:long enough body,:short,:long enough body"
+	if got := strings.Join(bodies, ","); got != want {
+		t.Fatalf(`expected "%s" bodies, got "%s"`, want, got)
+	}
+	if compression.Enabled("legacy") {
+		t.Fatal(`expected compression to be disabled for "legacy"`)
+	}
+}
+
+func Test_WithCompression_closesBody(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		ioutil.ReadAll(r.Body)
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	var closed int32
+	req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader("long enough body"))
+	req.Body = &trackedBody{strings.NewReader("long enough body"), &closed}
+	if _, err := Do(client, WithCompression(req, NewCompression(10))); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if atomic.LoadInt32(&closed) != 1 {
+		t.Fatal("expected the original body closed")
+	}
+}
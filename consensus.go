@@ -0,0 +1,158 @@
+package reqstrategy
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+)
+
+// Normalizer returns the representation of the response used to compare it with others,
+// e.g. the body with volatile fields removed, see NormalizeJSON and NormalizeHeaders
+type Normalizer func(*http.Response) ([]byte, error)
+
+// DivergenceError is returned by Consensus when not enough responses agree
+type DivergenceError struct {
+	// Groups lists indexes of the requests which responses agree with each other, largest group first
+	Groups [][]int
+	// Errs holds errors of the failed requests by index
+	Errs map[int]error
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("no consensus: responses diverged into groups %v, %d requests failed", e.Groups, len(e.Errs))
+}
+
+// Consensus runs requests simultaneously and returns the response agreed by at least majority of them,
+// e.g. to verify replicas consistency. Responses are compared by their normalized representation, the body
+// is used as is if normalize is <nil>. Otherwise *DivergenceError is returned. Every response body is read into
+// memory as soon as the response arrives, the returned one stays available for reading
+func Consensus(client *http.Client, majority int, normalize Normalizer, requests ...*http.Request) (*http.Response, error) {
+	requests = withStrategies(requests, "Consensus")
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
+	if normalize == nil {
+		normalize = func(r *http.Response) ([]byte, error) {
+			return ioutil.ReadAll(r.Body)
+		}
+	}
+
+	divergence := &DivergenceError{Errs: make(map[int]error)}
+	responses := make([]*http.Response, len(requests))
+	groups := make(map[string][]int)
+	results := run(client, requests, nil)
+	for range requests {
+		res := <-results
+		i, err := res.order, res.err
+		var key []byte
+		if err == nil {
+			key, err = normalized(res.response, normalize)
+		}
+		if err != nil {
+			closeBody(res.response)
+			divergence.Errs[i] = err
+			continue
+		}
+		responses[i] = res.response
+		groups[string(key)] = append(groups[string(key)], i)
+	}
+
+	divergence.Groups = sortGroups(groups)
+	if len(divergence.Groups) == 0 || len(divergence.Groups[0]) < majority {
+		return nil, divergence
+	}
+	return responses[divergence.Groups[0][0]], nil
+}
+
+// ConsensusDigest is Consensus comparing responses by digests of their bodies computed with newHash,
+// e.g. sha256.New, for payloads too large to hold every copy in memory. Every body is streamed through
+// the hash as soon as the response arrives, only the first body of every distinct digest is kept until
+// the majority is found, requests still in flight by then are cancelled and their bodies closed.
+// The returned response body stays available for reading
+func ConsensusDigest(client *http.Client, majority int, newHash func() hash.Hash, requests ...*http.Request) (*http.Response, error) {
+	requests = withStrategies(requests, "ConsensusDigest")
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
+
+	divergence := &DivergenceError{Errs: make(map[int]error)}
+	representatives := make(map[string]*http.Response)
+	groups := make(map[string][]int)
+	stop := make(chan struct{})
+	results := run(client, requests, stop)
+	for received := 1; received <= len(requests); received++ {
+		res := <-results
+		i := res.order
+		if res.err != nil {
+			closeBody(res.response)
+			divergence.Errs[i] = res.err
+			continue
+		}
+		digest, body, err := digested(res.response, newHash())
+		if err != nil {
+			divergence.Errs[i] = err
+			continue
+		}
+		key := string(digest)
+		if _, ok := representatives[key]; !ok {
+			res.response.Body = ioutil.NopCloser(bytes.NewReader(body))
+			representatives[key] = res.response
+		}
+		groups[key] = append(groups[key], i)
+		if len(groups[key]) >= majority {
+			close(stop)
+			for ; received < len(requests); received++ {
+				closeBody((<-results).response)
+			}
+			return representatives[key], nil
+		}
+	}
+
+	divergence.Groups = sortGroups(groups)
+	return nil, divergence
+}
+
+// digested reads and closes the response body returning its digest and the content
+func digested(resp *http.Response, h hash.Hash) ([]byte, []byte, error) {
+	var body bytes.Buffer
+	if resp.Body != nil {
+		_, err := io.Copy(io.MultiWriter(h, &body), resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return h.Sum(nil), body.Bytes(), nil
+}
+
+// sortGroups lists the groups of agreeing requests, largest group first, indexes in the group are sorted
+func sortGroups(groups map[string][]int) [][]int {
+	var sorted [][]int
+	for _, group := range groups {
+		sorted = append(sorted, group)
+	}
+	for _, group := range sorted {
+		sort.Ints(group)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		return len(a) > len(b) || len(a) == len(b) && a[0] < b[0]
+	})
+	return sorted
+}
+
+// normalized buffers the response body and passes the response to normalize, body is restored afterwards
+func normalized(resp *http.Response, normalize Normalizer) ([]byte, error) {
+	body, err := bufferBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}()
+	return normalize(resp)
+}
@@ -0,0 +1,135 @@
+package reqstrategy
+
+import (
+	"crypto/sha256"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_Consensus(t *testing.T) {
+	bodies := map[string]string{"/a": "v1", "/b": "v2", "/c": "v1"}
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/d" {
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		}
+		body := ioutil.NopCloser(strings.NewReader(bodies[r.URL.Path]))
+		return &http.Response{Request: r, StatusCode: 200, Body: body}, nil
+	})
+	requests := func() []*http.Request {
+		var requests []*http.Request
+		for _, path := range []string{"a", "b", "c", "d"} {
+			requests = append(requests, WithStatusRequired(newRequest(t, path), 200))
+		}
+		return requests
+	}
+
+	resp, err := Consensus(client, 2, nil, requests()...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "v1" {
+		t.Fatalf(`expected agreed "v1" body, got %q`, body)
+	}
+
+	_, err = Consensus(client, 3, nil, requests()...)
+	divergence, ok := err.(*DivergenceError)
+	if !ok {
+		t.Fatalf("expected *DivergenceError, got %v", err)
+	}
+	if len(divergence.Groups) != 2 || len(divergence.Groups[0]) != 2 || divergence.Groups[1][0] != 1 || divergence.Errs[3] == nil {
+		t.Fatalf("unexpected divergence %s", divergence)
+	}
+
+	resp, err = Consensus(client, 3, func(r *http.Response) ([]byte, error) {
+		return []byte("same"), nil
+	}, requests()...)
+	if err != nil {
+		t.Fatalf("expected normalized responses to agree, got %s", err)
+	}
+}
+
+func Test_ConsensusDigest(t *testing.T) {
+	var closed int32
+	bodies := map[string]string{"/a": "v1", "/b": "v2", "/c": "v1", "/d": "v1"}
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		body := &trackedBody{strings.NewReader(bodies[r.URL.Path]), &closed}
+		return &http.Response{Request: r, StatusCode: 200, Body: body}, nil
+	})
+	requests := func() []*http.Request {
+		var requests []*http.Request
+		for _, path := range []string{"a", "b", "c", "d"} {
+			requests = append(requests, newRequest(t, path))
+		}
+		return requests
+	}
+
+	resp, err := ConsensusDigest(client, 2, sha256.New, requests()...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "v1" {
+		t.Fatalf(`expected agreed "v1" body, got %q`, body)
+	}
+	if n := atomic.LoadInt32(&closed); n != 4 {
+		t.Fatalf("expected all 4 bodies closed, got %d", n)
+	}
+
+	_, err = ConsensusDigest(client, 4, sha256.New, requests()...)
+	divergence, ok := err.(*DivergenceError)
+	if !ok {
+		t.Fatalf("expected *DivergenceError, got %v", err)
+	}
+	if len(divergence.Groups) != 2 || len(divergence.Groups[0]) != 3 || divergence.Groups[1][0] != 1 {
+		t.Fatalf("unexpected divergence %s", divergence)
+	}
+}
+
+type trackedBody struct {
+	io.Reader
+	closed *int32
+}
+
+func (b *trackedBody) Close() error {
+	atomic.AddInt32(b.closed, 1)
+	return nil
+}
+
+func Test_Consensus_streamedBodies(t *testing.T) {
+	server := newStreamingServer()
+	defer server.Close()
+	var requests []*http.Request
+	for _, path := range []string{"/same", "/same", "/other"} {
+		r, _ := http.NewRequest("GET", server.URL+path, nil)
+		requests = append(requests, WithStatusRequired(r, 200))
+	}
+
+	resp, err := Consensus(server.Client(), 2, nil, requests...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if body, err := readBody(resp); err != nil || body != "/same" {
+		t.Fatalf(`expected agreed "/same" body, got %q, %v`, body, err)
+	}
+}
+
+func Test_ConsensusDigest_streamedBodies(t *testing.T) {
+	server := newStreamingServer()
+	defer server.Close()
+	var requests []*http.Request
+	for _, path := range []string{"/same", "/other", "/same"} {
+		r, _ := http.NewRequest("GET", server.URL+path, nil)
+		requests = append(requests, WithStatusRequired(r, 200))
+	}
+
+	resp, err := ConsensusDigest(server.Client(), 2, sha256.New, requests...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if body, err := readBody(resp); err != nil || body != "/same" {
+		t.Fatalf(`expected agreed "/same" body, got %q, %v`, body, err)
+	}
+}
@@ -0,0 +1,54 @@
+package reqstrategy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// AsCurl returns the curl command reproducing the attempted request. Values of Authorization,
+// Proxy-Authorization, Cookie and additionally listed headers are replaced with [REDACTED], so is the
+// password in the URL. Request body is included only if it can be obtained again with Request.GetBody
+func (a Attempt) AsCurl(redact ...string) string {
+	r := a.Request
+	redact = append([]string{"Authorization", "Proxy-Authorization", "Cookie"}, redact...)
+
+	cmd := []string{"curl"}
+	if r.Method != "" && r.Method != "GET" {
+		cmd = append(cmd, "-X", shellQuote(r.Method))
+	}
+
+	names := make([]string, 0, len(r.Header))
+	for name := range r.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range r.Header[name] {
+			for _, sensitive := range redact {
+				if http.CanonicalHeaderKey(sensitive) == name {
+					value = "[REDACTED]"
+				}
+			}
+			cmd = append(cmd, "-H", shellQuote(name+": "+value))
+		}
+	}
+
+	if r.GetBody != nil {
+		if body, err := r.GetBody(); err == nil {
+			data, err := ioutil.ReadAll(body)
+			body.Close()
+			if err == nil && len(data) != 0 {
+				cmd = append(cmd, "--data-binary", shellQuote(string(data)))
+			}
+		}
+	}
+
+	cmd = append(cmd, shellQuote(redactURL(r.URL)))
+	return strings.Join(cmd, " ")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
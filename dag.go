@@ -0,0 +1,185 @@
+package reqstrategy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrSkipped is reported for DAG nodes not run because a node they depend on failed
+var ErrSkipped = errors.New("skipped after dependency failure")
+
+// Node is a request in the dependency graph executed by RunDAG
+type Node struct {
+	// Name identifies the node in After, results and DAGError, it is required and has to be unique
+	Name string
+	// After lists names of the nodes which responses are needed to build the request
+	After []string
+	// Build makes the request from responses of the nodes listed in After keyed by name
+	Build func(deps map[string]*http.Response) (*http.Request, error)
+}
+
+// DAGError lists errors of the nodes which failed or were skipped with ErrSkipped
+type DAGError map[string]error
+
+func (e DAGError) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = name + ": " + e[name].Error()
+	}
+	return fmt.Sprintf("%d requests failed: %s", len(e), strings.Join(messages, "; "))
+}
+
+// RunDAG executes nodes after the ones they depend on, running independent branches concurrently.
+// Requests are sent with Do so every node is validated, failed node only fails the nodes depending on it.
+// Response bodies are buffered in memory so every dependent node gets its own copy to read.
+// Responses of successful nodes are returned keyed by name, along with DAGError if any node failed.
+// Nothing is sent if a node has no name, its name is not unique or dependencies make a cycle
+func RunDAG(client *http.Client, nodes ...Node) (map[string]*http.Response, error) {
+	names := make([]string, len(nodes))
+	after := make([][]string, len(nodes))
+	for i, n := range nodes {
+		if n.Name == "" {
+			return nil, fmt.Errorf("node #%d has no name", i)
+		}
+		names[i], after[i] = n.Name, n.After
+	}
+	order, err := planDependencies(names, after)
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	bodies := make(map[string][]byte, len(nodes))
+	responses := make(map[string]*http.Response, len(nodes))
+	failures := make(DAGError)
+	done := make(map[string]chan struct{}, len(nodes))
+	for _, n := range nodes {
+		done[n.Name] = make(chan struct{})
+	}
+	// response returns the copy of the node response with its own body reader
+	response := func(name string) *http.Response {
+		resp := *responses[name]
+		resp.Body = ioutil.NopCloser(bytes.NewReader(bodies[name]))
+		return &resp
+	}
+
+	wg.Add(len(nodes))
+	for _, i := range order {
+		n := nodes[i]
+		spawn(func() {
+			defer wg.Done()
+			defer close(done[n.Name])
+
+			deps := make(map[string]*http.Response, len(n.After))
+			for _, name := range n.After {
+				<-done[name]
+				mu.Lock()
+				_, ok := responses[name]
+				if !ok {
+					failures[n.Name] = ErrSkipped
+					mu.Unlock()
+					return
+				}
+				deps[name] = response(name)
+				mu.Unlock()
+			}
+
+			resp, err := buildAndDo(client, n, deps)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				closeBody(resp)
+				failures[n.Name] = err
+				return
+			}
+			responses[n.Name], bodies[n.Name] = resp, nil
+			if resp.Body != nil {
+				bodies[n.Name], err = ioutil.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					delete(responses, n.Name)
+					failures[n.Name] = err
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	result := make(map[string]*http.Response, len(responses))
+	for name := range responses {
+		result[name] = response(name)
+	}
+	if len(failures) != 0 {
+		return result, failures
+	}
+	return result, nil
+}
+
+func buildAndDo(client *http.Client, n Node, deps map[string]*http.Response) (*http.Response, error) {
+	r, err := n.Build(deps)
+	if err != nil {
+		return nil, err
+	}
+	return Do(client, r)
+}
+
+// planDependencies orders items named by names so every item follows the ones listed in its after
+func planDependencies(names []string, after [][]string) ([]int, error) {
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, ok := index[name]; ok {
+			return nil, fmt.Errorf("duplicate name %q", name)
+		}
+		index[name] = i
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make([]int, len(names))
+	order := make([]int, 0, len(names))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle at %q", names[i])
+		}
+		state[i] = visiting
+		for _, name := range after[i] {
+			dep, ok := index[name]
+			if !ok {
+				return fmt.Errorf("%q depends on unknown %q", names[i], name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[i] = visited
+		order = append(order, i)
+		return nil
+	}
+	for i := range names {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,91 @@
+package reqstrategy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func Test_RunDAG(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/broken" {
+			return &http.Response{StatusCode: 500, Request: r}, nil
+		}
+		body := ioutil.NopCloser(strings.NewReader("token-" + strings.TrimPrefix(r.URL.Path, "/")))
+		return &http.Response{StatusCode: 200, Request: r, Body: body}, nil
+	})
+	get := func(path string) func(map[string]*http.Response) (*http.Request, error) {
+		return func(map[string]*http.Response) (*http.Request, error) {
+			return WithStatusRequired(newRequest(t, path), 200), nil
+		}
+	}
+
+	responses, err := RunDAG(client,
+		Node{Name: "orders", After: []string{"login"}, Build: func(deps map[string]*http.Response) (*http.Request, error) {
+			token, _ := ioutil.ReadAll(deps["login"].Body)
+			return WithStatusRequired(newRequest(t, "orders", string(token)), 200), nil
+		}},
+		Node{Name: "profile", After: []string{"login"}, Build: get("profile")},
+		Node{Name: "login", Build: get("login")},
+		Node{Name: "broken", Build: get("broken")},
+		Node{Name: "report", After: []string{"broken", "orders"}, Build: get("report")},
+	)
+
+	failures, ok := err.(DAGError)
+	if !ok || len(failures) != 2 || failures["report"] != ErrSkipped || failures["broken"] == nil {
+		t.Fatalf("expected broken to fail and report to be skipped, got %v", err)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("expected 3 responses, got %d", len(responses))
+	}
+	body, _ := ioutil.ReadAll(responses["orders"].Body)
+	if string(body) != "token-orders/token-login" {
+		t.Fatalf("expected orders built from login response, got %q", body)
+	}
+	body, _ = ioutil.ReadAll(responses["login"].Body)
+	if string(body) != "token-login" {
+		t.Fatalf("expected login body to stay readable, got %q", body)
+	}
+}
+
+func Test_RunDAG_unknown(t *testing.T) {
+	_, err := RunDAG(http.DefaultClient, Node{Name: "a", After: []string{"b"}})
+	if err == nil {
+		t.Fatal("expected unknown dependency error")
+	}
+}
+
+func Test_RunDAG_unnamed(t *testing.T) {
+	var sent int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		sent++
+		return &http.Response{StatusCode: 200, Request: r}, nil
+	})
+	build := func(map[string]*http.Response) (*http.Request, error) {
+		return newRequest(t), nil
+	}
+
+	_, err := RunDAG(client, Node{Build: build}, Node{Build: build})
+	if err == nil || sent != 0 {
+		t.Fatalf("expected unnamed nodes rejected before sending, got %v after %d requests", err, sent)
+	}
+}
+
+func Test_RunDAG_closesFailed(t *testing.T) {
+	var closed int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 500, Request: r, Body: &trackedBody{strings.NewReader("error"), &closed}}, nil
+	})
+	build := func(map[string]*http.Response) (*http.Request, error) {
+		return WithStatusRequired(newRequest(t), 200), nil
+	}
+
+	if _, err := RunDAG(client, Node{Name: "a", Build: build}); err == nil {
+		t.Fatal("expected validation error")
+	}
+	if atomic.LoadInt32(&closed) != 1 {
+		t.Fatal("expected the body of the failed node closed")
+	}
+}
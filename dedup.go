@@ -0,0 +1,109 @@
+package reqstrategy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DuplicateError is returned when the request is not sent because the same request was sent recently,
+// see Deduplicator
+type DuplicateError struct {
+	Request *http.Request
+	// Sent is when the original request was sent
+	Sent time.Time
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("%s %s: duplicate of the request sent at %s", e.Request.Method, e.Request.URL, e.Sent.Format(time.RFC3339Nano))
+}
+
+// Deduplicator remembers fingerprints (method, URL and body hash) of the requests sent within ttl, guarding
+// against accidental double submits of requests with side effects. Attempts made by strategies like Retry
+// are not considered duplicates of each other. It is safe for concurrent use
+type Deduplicator struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	sent  map[string]time.Time
+	swept time.Time
+}
+
+// NewDeduplicator creates Deduplicator remembering requests for ttl
+func NewDeduplicator(ttl time.Duration) *Deduplicator {
+	return &Deduplicator{ttl: ttl, sent: make(map[string]time.Time)}
+}
+
+// WithDeduplicator makes the request fail with *DuplicateError instead of being sent if the same request
+// was sent within the Deduplicator ttl
+func WithDeduplicator(r *http.Request, d *Deduplicator) *http.Request {
+	return withValue(r, keyDeduplicator, d)
+}
+
+// remember records the request as sent at now, it returns the time the same request was sent at
+// and false if it is still remembered
+func (d *Deduplicator) remember(fingerprint string, now time.Time) (time.Time, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if now.Sub(d.swept) >= d.ttl {
+		for f, sent := range d.sent {
+			if now.Sub(sent) >= d.ttl {
+				delete(d.sent, f)
+			}
+		}
+		d.swept = now
+	}
+	if sent, ok := d.sent[fingerprint]; ok && now.Sub(sent) < d.ttl {
+		return sent, false
+	}
+	d.sent[fingerprint] = now
+	return now, true
+}
+
+// deduplicate checks the first attempt of the request against its Deduplicator, the body is read
+// with GetBody or buffered if there is none, so the request can be sent again
+func deduplicate(r *http.Request) (*http.Request, error) {
+	d, _ := r.Context().Value(keyDeduplicator).(*Deduplicator)
+	if attempt, _ := r.Context().Value(keyAttempt).(int); d == nil || attempt > 1 {
+		return r, nil
+	}
+
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.String()+"\n")
+	if r.Body != nil && r.Body != http.NoBody {
+		if r.GetBody == nil {
+			body, err := ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			r = r.WithContext(r.Context())
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+			r.GetBody = func() (io.ReadCloser, error) {
+				return ioutil.NopCloser(bytes.NewReader(body)), nil
+			}
+			h.Write(body)
+		} else {
+			body, err := r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			_, err = io.Copy(h, body)
+			body.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if sent, ok := d.remember(hex.EncodeToString(h.Sum(nil)), clockOf(r).Now()); !ok {
+		return nil, &DuplicateError{Request: r, Sent: sent}
+	}
+	return r, nil
+}
@@ -0,0 +1,48 @@
+package reqstrategy
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy/reqstrategytest"
+)
+
+func Test_Deduplicator(t *testing.T) {
+	var calls int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("connection reset")
+		}
+		return &http.Response{StatusCode: 200, Request: r, Body: http.NoBody}, nil
+	})
+	dedup := NewDeduplicator(time.Minute)
+	start := time.Now()
+	clock := reqstrategytest.NewVirtualClock(start)
+	order := func(body string) *http.Request {
+		r, _ := http.NewRequest("POST", "http://localhost/orders", strings.NewReader(body))
+		return WithClock(WithDeduplicator(r, dedup), clock)
+	}
+
+	if _, err := Retry(client, order("a"), time.Millisecond); err != nil {
+		t.Fatalf("expected retried request to succeed, got %s", err)
+	}
+	_, err := Do(client, order("a"))
+	if dup, ok := err.(*DuplicateError); !ok || !dup.Sent.Equal(start) {
+		t.Fatalf("expected *DuplicateError, got %v", err)
+	}
+	if _, err := Do(client, order("b")); err != nil {
+		t.Fatalf("expected request with different body to be sent, got %s", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+
+	<-clock.After(time.Minute)
+	if _, err := Do(client, order("a")); err != nil {
+		t.Fatalf("expected request to be sent once ttl passed, got %s", err)
+	}
+}
@@ -0,0 +1,29 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"time"
+)
+
+// DualWrite sends the write to both primary and secondary, e.g. to the old and the new storage during
+// the migration, returning the validated primary response as if it was sent with Do. Secondary is sent
+// in background, retrying after intervals if it fails (see Retry), and never affects the caller: its
+// outcome is only reported to hooks and stats, see WithHook and WithStats. Its response body is read and closed.
+// Unlike Mirror, secondary attempts carry the attempt metadata and its body is sent again the same way as by Retry
+func DualWrite(client *http.Client, primary, secondary *http.Request, intervals ...time.Duration) (*http.Response, error) {
+	secondary, err := rewindable(withChildID(withStrategy(secondary, "DualWrite"), 2), MaxBufferedBody)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		resp, _ := retry(secondary, intervals, func(attempt int) (*http.Response, error) {
+			r, err := rewind(secondary, attempt)
+			if err != nil {
+				return nil, err
+			}
+			return Do(client, withAttempt(r, attempt))
+		})
+		Discard(resp)
+	}()
+	return Do(client, withChildID(withStrategy(primary, "DualWrite"), 1))
+}
@@ -0,0 +1,41 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_DualWrite(t *testing.T) {
+	var secondaryCalls int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/secondary" && atomic.AddInt32(&secondaryCalls, 1) == 1 {
+			return &http.Response{Request: r, StatusCode: 503, Body: http.NoBody}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 201, Body: http.NoBody}, nil
+	})
+	attempts := make(chan Attempt, 2)
+	newWrite := func(path string) *http.Request {
+		r, _ := http.NewRequest("PUT", "http://localhost/"+path, strings.NewReader("data"))
+		return WithStatusRequired(r, 201)
+	}
+	secondary := WithHook(newWrite("secondary"), func(a Attempt) {
+		attempts <- a
+	})
+
+	resp, err := DualWrite(client, newWrite("primary"), secondary, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.Request.URL.Path != "/primary" {
+		t.Fatalf(`expected response of "/primary", got "%s"`, resp.Request.URL.Path)
+	}
+	if a := <-attempts; a.Err == nil {
+		t.Fatalf("expected the first secondary attempt to fail")
+	}
+	if a := <-attempts; a.Err != nil || a.Response.StatusCode != 201 {
+		t.Fatalf("expected the secondary retried, got %v", a.Err)
+	}
+}
@@ -0,0 +1,127 @@
+package reqstrategy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Dump is a wire representation of a single attempt
+type Dump struct {
+	CallID   string
+	Request  []byte
+	Response []byte
+	Err      error
+}
+
+// DumpBundle collects dumps of every attempt made for the requests it is attached to with WithDumps,
+// so the failed strategy can be shared for debugging. Values of sensitive headers are redacted.
+// Dumping reads the whole request and response bodies into memory, so it is not suitable for streaming.
+// It is safe for concurrent use
+type DumpBundle struct {
+	redact []string
+	// limit is the number of the last dumps kept, all are kept if zero
+	limit int
+
+	mu    sync.Mutex
+	dumps []Dump
+}
+
+// sensitiveHeaders are always redacted in dumps and audit records
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// NewDumpBundle creates DumpBundle redacting listed headers in addition to
+// Authorization, Proxy-Authorization, Cookie and Set-Cookie
+func NewDumpBundle(redact ...string) *DumpBundle {
+	return &DumpBundle{redact: append(sensitiveHeaders[:len(sensitiveHeaders):len(sensitiveHeaders)], redact...)}
+}
+
+// WithDumps makes Do record the request and response dumps of every attempt into the bundle
+func WithDumps(r *http.Request, b *DumpBundle) *http.Request {
+	return withValue(r, keyDumps, b)
+}
+
+// Dumps returns the attempts recorded so far
+func (b *DumpBundle) Dumps() []Dump {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Dump(nil), b.dumps...)
+}
+
+// WriteDir writes every attempt into the directory as the pair of NNN-request.txt and NNN-response.txt files,
+// the transport error is written instead of the response when there was no response
+func (b *DumpBundle) WriteDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, d := range b.Dumps() {
+		response := d.Response
+		if response == nil && d.Err != nil {
+			response = []byte(d.Err.Error())
+		}
+		files := map[string][]byte{"request": d.Request, "response": response}
+		for name, data := range files {
+			path := filepath.Join(dir, fmt.Sprintf("%03d-%s.txt", i+1, name))
+			if err := ioutil.WriteFile(path, data, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// startDump dumps the request if it has a bundle attached, returning the request with the body
+// restored and the function recording the outcome
+func startDump(id string, r *http.Request) (*http.Request, func(*http.Response, error)) {
+	b, ok := r.Context().Value(keyDumps).(*DumpBundle)
+	if !ok {
+		return r, func(*http.Response, error) {}
+	}
+
+	dumped := r.WithContext(r.Context())
+	dumped.Header = b.redacted(r.Header)
+	request, err := httputil.DumpRequestOut(dumped, true)
+	if err != nil {
+		request = []byte(err.Error())
+	}
+	r = r.WithContext(r.Context())
+	r.Body = dumped.Body
+
+	return r, func(resp *http.Response, err error) {
+		d := Dump{CallID: id, Request: request, Err: err}
+		if resp != nil {
+			header := resp.Header
+			resp.Header = b.redacted(header)
+			d.Response, _ = httputil.DumpResponse(resp, resp.Body != nil)
+			resp.Header = header
+		}
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.dumps = append(b.dumps, d)
+		if b.limit > 0 && len(b.dumps) > b.limit {
+			b.dumps = b.dumps[len(b.dumps)-b.limit:]
+		}
+	}
+}
+
+func (b *DumpBundle) redacted(h http.Header) http.Header {
+	return redactHeader(h, b.redact)
+}
+
+// redactHeader returns the copy of the header with values of listed headers replaced
+func redactHeader(h http.Header, redact []string) http.Header {
+	redacted := make(http.Header, len(h))
+	for k, v := range h {
+		redacted[k] = v
+	}
+	for _, name := range redact {
+		if redacted.Get(name) != "" {
+			redacted.Set(name, "[REDACTED]")
+		}
+	}
+	return redacted
+}
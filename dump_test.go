@@ -0,0 +1,62 @@
+package reqstrategy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_WithDumps(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			Request:    r,
+			StatusCode: 500,
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Set-Cookie": {"session=secret"}},
+			Body:       ioutil.NopCloser(strings.NewReader("oops")),
+		}, nil
+	})
+
+	bundle := NewDumpBundle()
+	req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader("payload"))
+	req.Header.Set("Authorization", "Bearer secret")
+	req = WithDumps(WithStatusRequired(req, 200), bundle)
+	if _, err := Retry(client, req, time.Millisecond); err == nil {
+		t.Fatal("expected error")
+	}
+
+	dumps := bundle.Dumps()
+	if len(dumps) != 2 {
+		t.Fatalf("expected 2 dumps, got %d", len(dumps))
+	}
+	for _, d := range dumps {
+		if bytes.Contains(d.Request, []byte("secret")) || bytes.Contains(d.Response, []byte("secret")) {
+			t.Fatalf("expected secrets to be redacted, got %s\n%s", d.Request, d.Response)
+		}
+		if !bytes.Contains(d.Response, []byte("oops")) {
+			t.Fatalf("expected response body to be dumped, got %s", d.Response)
+		}
+	}
+	if !bytes.Contains(dumps[0].Request, []byte("payload")) {
+		t.Fatalf("expected request body to be dumped, got %s", dumps[0].Request)
+	}
+
+	dir, err := ioutil.TempDir("", "dumps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := bundle.WriteDir(dir); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if len(files) != 4 {
+		t.Fatalf("expected 4 files, got %v", files)
+	}
+}
@@ -0,0 +1,63 @@
+package reqstrategy
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// Estimate is the cost of a single call executed according to the policy, see Policy.Estimate
+type Estimate struct {
+	// Attempts is the number of attempts in the worst case, retries which MaxElapsed cuts off are not counted
+	Attempts int
+	// Requests is the number of requests sent in the worst case, i.e. the request amplification
+	Requests int
+	// Backoff is the latency added by waiting between attempts in the worst case
+	Backoff time.Duration
+	// ExpectedRequests is the mean number of requests sent given the failure rate
+	ExpectedRequests float64
+	// ExtraLoad is the share of requests backends get on top of a single request per call given the failure rate,
+	// e.g. 0.5 for 50% more requests
+	ExtraLoad float64
+}
+
+// Estimate reports the worst-case request amplification and added latency of the call made according to the policy,
+// and the expected load assuming every request fails independently with the failure rate, from 0 to 1.
+// Requests raced across Endpoints are all counted, as losers are cancelled only after they were sent, so are
+// all Hedges
+func (p Policy) Estimate(failureRate float64) Estimate {
+	e := Estimate{Attempts: 1}
+	for _, interval := range p.Intervals {
+		if p.MaxElapsed > 0 && e.Backoff+interval > p.MaxElapsed {
+			break
+		}
+		e.Attempts++
+		e.Backoff += interval
+	}
+
+	legs := p.width()
+	e.Requests = e.Attempts * legs
+
+	// attempt is repeated when all of its legs failed
+	repeated := math.Pow(failureRate, float64(legs))
+	var attempts float64
+	for n := 0; n < e.Attempts; n++ {
+		attempts += math.Pow(repeated, float64(n))
+	}
+	e.ExpectedRequests = attempts * float64(legs)
+	e.ExtraLoad = e.ExpectedRequests - 1
+	return e
+}
+
+// MarshalJSON encodes the estimate as
+//
+//	{"attempts": 3, "requests": 6, "backoff_ms": 3000, "expected_requests": 2.02, "extra_load": 1.02}
+func (e Estimate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Attempts         int     `json:"attempts"`
+		Requests         int     `json:"requests"`
+		Backoff          float64 `json:"backoff_ms"`
+		ExpectedRequests float64 `json:"expected_requests"`
+		ExtraLoad        float64 `json:"extra_load"`
+	}{e.Attempts, e.Requests, milliseconds(e.Backoff), e.ExpectedRequests, e.ExtraLoad})
+}
@@ -0,0 +1,39 @@
+package reqstrategy
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func Test_Policy_Estimate(t *testing.T) {
+	p := Policy{
+		Intervals:  []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		MaxElapsed: 5 * time.Second,
+		Endpoints:  []string{"https://eu.example.com", "https://us.example.com"},
+	}
+
+	e := p.Estimate(0.1)
+	if e.Attempts != 3 || e.Requests != 6 || e.Backoff != 3*time.Second {
+		t.Fatalf("expected 3 attempts, 6 requests and 3s of backoff, got %+v", e)
+	}
+	if math.Abs(e.ExpectedRequests-2.0202) > 1e-9 || math.Abs(e.ExtraLoad-1.0202) > 1e-9 {
+		t.Fatalf("expected 2.0202 requests per call, got %+v", e)
+	}
+
+	p.Endpoints = append(p.Endpoints, "https://ap.example.com")
+	p.Hedges = 1
+	if e := p.Estimate(0.1); e.Requests != 6 {
+		t.Fatalf("expected 6 requests with a single hedge, got %+v", e)
+	}
+
+	data, err := json.Marshal(Policy{}.Estimate(0.5))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := `{"attempts":1,"requests":1,"backoff_ms":0,"expected_requests":1,"extra_load":0}`
+	if string(data) != expected {
+		t.Fatalf("expected %s, got %s", expected, data)
+	}
+}
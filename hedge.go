@@ -0,0 +1,94 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"time"
+)
+
+// Hedge sends the request and, if no response is received within delay, sends up to maxHedges copies
+// of it one by one after the same delay, returning the first successful response. A copy is sent right
+// away if all requests in flight failed. Once the result is determined the rest of requests are cancelled
+// through the context, the returned one keeps running until its body is closed. Error of the last request
+// is returned if all of them failed. Request body is sent again the same way as by Retry, request marked
+// with WithNoHedge is sent once
+func Hedge(client *http.Client, request *http.Request, delay time.Duration, maxHedges int) (*http.Response, error) {
+	request = withStrategy(request, "Hedge")
+	if noHedge(request) {
+		maxHedges = 0
+	}
+	request, err := rewindable(request, MaxBufferedBody)
+	if err != nil {
+		return nil, err
+	}
+	if err := planRequests([]*http.Request{request}, maxHedges+1); err != nil {
+		return nil, err
+	}
+	// copies of the request are not duplicates, the request is checked once
+	request, err = deduplicate(request)
+	if err != nil {
+		return nil, err
+	}
+	request = withValue(request, keyDeduplicator, (*Deduplicator)(nil))
+	results := make(chan result, maxHedges+1)
+	clock := clockOf(request)
+
+	var launched, received int
+	var timer <-chan time.Time
+	var stops []chan struct{}
+	winner := -1
+	launch := func() error {
+		r, err := rewind(request, launched+1)
+		if err != nil {
+			return err
+		}
+		order := launched
+		launched++
+		stop := make(chan struct{})
+		stops = append(stops, stop)
+		// hedges are driven by time, so they are sent concurrently even in deterministic mode
+		go do(client, r, order, stop, results)
+		timer = nil
+		if launched <= maxHedges {
+			timer = clock.After(delay)
+		}
+		return nil
+	}
+
+	if err := launch(); err != nil {
+		return nil, err
+	}
+	// copies which lost are cancelled once Hedge returns, the winner is not
+	defer func() {
+		for i, stop := range stops {
+			if i != winner {
+				close(stop)
+			}
+		}
+		closeResults(results, launched-received)
+	}()
+	for {
+		select {
+		case res := <-results:
+			received++
+			if res.err == nil {
+				winner = res.order
+				return res.response, nil
+			}
+			if received < launched {
+				continue
+			}
+			if launched > maxHedges {
+				return nil, res.err
+			}
+			if err := launch(); err != nil {
+				return nil, err
+			}
+		case <-timer:
+			if err := launch(); err != nil {
+				return nil, err
+			}
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		}
+	}
+}
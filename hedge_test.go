@@ -0,0 +1,79 @@
+package reqstrategy
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Hedge(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		first := len(bodies) == 1
+		mu.Unlock()
+		if first {
+			select {
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			case <-time.After(time.Second):
+			}
+		}
+		return &http.Response{StatusCode: 200, Request: r}, nil
+	})
+
+	req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader("payload"))
+	start := time.Now()
+	resp, err := Hedge(client, req, 10*time.Millisecond, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.StatusCode != 200 || time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("expected hedged request to win, took %s", time.Since(start))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 2 || bodies[1] != "payload" {
+		t.Fatalf("expected one hedge with the same body, got %q", bodies)
+	}
+}
+
+func Test_Hedge_failed(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		return nil, errors.New("request failed")
+	})
+
+	_, err := Hedge(client, newRequest(t), time.Hour, 2)
+	if err == nil || !strings.Contains(err.Error(), "request failed") {
+		t.Fatalf("expected request error, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected failed requests to be hedged right away, got %d calls", calls)
+	}
+}
+
+func Test_Hedge_streamedBody(t *testing.T) {
+	server := newStreamingServer()
+	defer server.Close()
+	req, _ := http.NewRequest("GET", server.URL+"/hedged", nil)
+
+	resp, err := Hedge(server.Client(), WithStatusRequired(req, 200), 5*time.Millisecond, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if body, err := readBody(resp); err != nil || body != "/hedged" {
+		t.Fatalf("expected the winner body readable, got %q, %v", body, err)
+	}
+}
@@ -0,0 +1,122 @@
+package reqstrategy
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets spans 100µs to about 3 minutes with 10% relative error, see ExponentialBuckets
+var DefaultBuckets = ExponentialBuckets(100*time.Microsecond, 1.1, 152)
+
+// ExponentialBuckets returns n bucket upper bounds starting at start and growing by factor, the relative
+// error of quantiles is bounded by factor-1 like in HDR histograms
+func ExponentialBuckets(start time.Duration, factor float64, n int) []time.Duration {
+	bounds := make([]time.Duration, n)
+	for i := range bounds {
+		bounds[i] = time.Duration(float64(start) * math.Pow(factor, float64(i)))
+	}
+	return bounds
+}
+
+// LinearBuckets returns n bucket upper bounds starting at start and growing by width
+func LinearBuckets(start, width time.Duration, n int) []time.Duration {
+	bounds := make([]time.Duration, n)
+	for i := range bounds {
+		bounds[i] = start + time.Duration(i)*width
+	}
+	return bounds
+}
+
+// Histogram aggregates durations into buckets so quantiles can be reported without keeping
+// raw samples. It is safe for concurrent use
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []time.Duration
+	counts []int64
+	total  int64
+	sum    time.Duration
+	max    time.Duration
+}
+
+// NewHistogram creates the histogram with provided bucket upper bounds, DefaultBuckets are used
+// if none provided. Durations above the last bound are counted in the overflow bucket
+func NewHistogram(bounds ...time.Duration) *Histogram {
+	if len(bounds) == 0 {
+		bounds = DefaultBuckets
+	}
+	bounds = append([]time.Duration(nil), bounds...)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+	return &Histogram{bounds: bounds, counts: make([]int64, len(bounds)+1)}
+}
+
+// Observe records the duration
+func (h *Histogram) Observe(d time.Duration) {
+	i := sort.Search(len(h.bounds), func(i int) bool { return h.bounds[i] >= d })
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.total++
+	h.sum += d
+	if d > h.max {
+		h.max = d
+	}
+}
+
+// Count returns the number of observed durations
+func (h *Histogram) Count() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.total
+}
+
+// Mean returns the mean of observed durations
+func (h *Histogram) Mean() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.total)
+}
+
+// Max returns the largest observed duration
+func (h *Histogram) Max() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.max
+}
+
+// Quantile returns the upper bound of the bucket holding the q-th quantile, e.g. 0.999 for P999,
+// capped by the largest observed duration. Zero is returned if nothing was observed
+func (h *Histogram) Quantile(q float64) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(h.total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, count := range h.counts {
+		seen += count
+		if seen >= rank && i < len(h.bounds) && h.bounds[i] < h.max {
+			return h.bounds[i]
+		}
+		if seen >= rank {
+			break
+		}
+	}
+	return h.max
+}
+
+// Buckets returns bucket upper bounds along with the counts of durations in every bucket,
+// counts have one more element for durations above the last bound
+func (h *Histogram) Buckets() ([]time.Duration, []int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]time.Duration(nil), h.bounds...), append([]int64(nil), h.counts...)
+}
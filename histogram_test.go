@@ -0,0 +1,41 @@
+package reqstrategy
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Histogram(t *testing.T) {
+	h := NewHistogram(LinearBuckets(time.Millisecond, time.Millisecond, 100)...)
+	for i := 1; i <= 1000; i++ {
+		h.Observe(time.Duration(i) * 100 * time.Microsecond)
+	}
+
+	if h.Count() != 1000 {
+		t.Fatalf("expected 1000 observations, got %d", h.Count())
+	}
+	if q := h.Quantile(0.5); q != 50*time.Millisecond {
+		t.Fatalf("expected P50 of 50ms, got %s", q)
+	}
+	if q := h.Quantile(0.99); q != 99*time.Millisecond {
+		t.Fatalf("expected P99 of 99ms, got %s", q)
+	}
+	if q := h.Quantile(1); q != 100*time.Millisecond {
+		t.Fatalf("expected P100 of 100ms, got %s", q)
+	}
+	if m := h.Mean(); m != 50050*time.Microsecond {
+		t.Fatalf("expected mean of 50.05ms, got %s", m)
+	}
+}
+
+func Test_Histogram_overflow(t *testing.T) {
+	h := NewHistogram(time.Millisecond)
+	h.Observe(time.Second)
+	if q := h.Quantile(0.99); q != time.Second {
+		t.Fatalf("expected overflow to be reported as max, got %s", q)
+	}
+	_, counts := h.Buckets()
+	if len(counts) != 2 || counts[1] != 1 {
+		t.Fatalf("expected overflow bucket to be counted, got %v", counts)
+	}
+}
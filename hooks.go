@@ -0,0 +1,134 @@
+package reqstrategy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// CallIDHeader is the request header carrying the call ID, see WithCallID
+const CallIDHeader = "X-Call-Id"
+
+// ContextKey is the type of context keys available to transports and middleware
+type ContextKey string
+
+const (
+	// StrategyKey holds the name of the outermost strategy the attempt is made by, e.g. "Race"
+	StrategyKey ContextKey = "reqstrategy.strategy"
+	// CallNameKey holds the logical call name, see WithCallName
+	CallNameKey ContextKey = "reqstrategy.call-name"
+)
+
+// Attempt describes a single Do call made on its own or as a part of a strategy
+type Attempt struct {
+	CallID   string
+	Request  *http.Request
+	Response *http.Response
+	Err      error
+	Start    time.Time
+	Duration time.Duration
+	// Canceled tells the attempt was cancelled by the strategy once its outcome was determined, e.g. the
+	// loser of Race or the rest of All after a failure. Err is set but it is not a failure of the request
+	Canceled bool
+}
+
+// WithHook adds the function called after every attempt to send the request, the same
+// request may be attempted multiple times by strategies like Retry. Hooks are called
+// synchronously so they should not block
+func WithHook(r *http.Request, hook func(Attempt)) *http.Request {
+	hooks, _ := r.Context().Value(keyHooks).([]func(Attempt))
+	return withValue(r, keyHooks, append(hooks[:len(hooks):len(hooks)], hook))
+}
+
+// WithApproval makes every attempt wait for approve to return before the request is sent, allowing
+// to step through strategies interactively. Attempt passed to approve has only CallID, Request and Start set,
+// see Attempt.AsCurl for the request preview. The attempt fails with the error approve returned, if any
+func WithApproval(r *http.Request, approve func(Attempt) error) *http.Request {
+	return withValue(r, keyApproval, approve)
+}
+
+// WithCallID sets the ID of the logical call the request belongs to. Strategies derive hierarchical
+// IDs for every attempt they make, e.g. "abc.2.1" is the first attempt of the second request raced
+// within call "abc". The attempt ID is sent in CallIDHeader and reported in Attempt.CallID
+func WithCallID(r *http.Request, id string) *http.Request {
+	return withValue(r, keyCallID, id)
+}
+
+// WithCallName sets the logical name of the call, e.g. "get-profile", made available to transports
+// and middleware through every attempt's context under CallNameKey
+func WithCallName(r *http.Request, name string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), CallNameKey, name))
+}
+
+// NewCallID generates a random call ID for WithCallID
+func NewCallID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func approve(id string, r *http.Request) error {
+	if approve, ok := r.Context().Value(keyApproval).(func(Attempt) error); ok {
+		return approve(Attempt{CallID: id, Request: r, Start: time.Now()})
+	}
+	return nil
+}
+
+// withStopped adds the flag set once the strategy cancels the request, flags of outer strategies are kept
+func withStopped(r *http.Request, stopped *int32) *http.Request {
+	flags, _ := r.Context().Value(keyStopped).([]*int32)
+	return withValue(r, keyStopped, append(flags[:len(flags):len(flags)], stopped))
+}
+
+// stoppedByStrategy tells whether the request was cancelled by any strategy it is sent by, see withStopped
+func stoppedByStrategy(r *http.Request) bool {
+	flags, _ := r.Context().Value(keyStopped).([]*int32)
+	for _, stopped := range flags {
+		if atomic.LoadInt32(stopped) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func notify(r *http.Request, a Attempt) {
+	hooks, _ := r.Context().Value(keyHooks).([]func(Attempt))
+	for _, hook := range hooks {
+		hook(a)
+	}
+}
+
+// withStrategy marks the request as sent by the strategy, unless it is already a part of another strategy
+func withStrategy(r *http.Request, name string) *http.Request {
+	if _, ok := r.Context().Value(StrategyKey).(string); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), StrategyKey, name))
+}
+
+// withStrategies marks requests as sent by the strategy, see withStrategy
+func withStrategies(requests []*http.Request, name string) []*http.Request {
+	marked := make([]*http.Request, len(requests))
+	for i, r := range requests {
+		marked[i] = withStrategy(r, name)
+	}
+	return marked
+}
+
+// withAttempt marks the request as the n-th attempt of the same call
+func withAttempt(r *http.Request, n int) *http.Request {
+	return withValue(withChildID(r, n), keyAttempt, n)
+}
+
+// withChildID derives the call ID for the n-th sub-call of the request, if it has an ID
+func withChildID(r *http.Request, n int) *http.Request {
+	id, ok := r.Context().Value(keyCallID).(string)
+	if !ok {
+		return r
+	}
+	return withValue(r, keyCallID, id+"."+strconv.Itoa(n))
+}
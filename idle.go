@@ -0,0 +1,140 @@
+package reqstrategy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrIdleTimeout is returned by response body reads when no bytes arrived in time, see WithIdleTimeout
+var ErrIdleTimeout = errors.New("response idle timeout")
+
+type idleWatch struct {
+	timeout   time.Duration
+	reconnect func(r *http.Request, received int64) (*http.Request, error)
+}
+
+// WithIdleTimeout aborts the attempt when no bytes of the response arrive for d, telling silently dead
+// connections apart from slow but alive streams, e.g. SSE, NDJSON or long downloads. Once the body went idle
+// the reads fail with ErrIdleTimeout, unless reconnect is provided. Then the connection is dropped and
+// the request built by reconnect from the original one and the number of body bytes received so far
+// (e.g. with Range or Last-Event-ID header) is sent, its response body continues the original one.
+// Reconnect may return an error to give up. Idle timeout covers waiting for the response headers as well
+func WithIdleTimeout(r *http.Request, d time.Duration, reconnect func(r *http.Request, received int64) (*http.Request, error)) *http.Request {
+	return withValue(r, keyIdleTimeout, idleWatch{d, reconnect})
+}
+
+// startIdle starts watching the request for inactivity, it returns the request to send and the function
+// wrapping its outcome
+func startIdle(client *http.Client, r *http.Request) (*http.Request, func(*http.Response, error) (*http.Response, error)) {
+	watch, _ := r.Context().Value(keyIdleTimeout).(idleWatch)
+	if watch.timeout <= 0 {
+		return r, func(resp *http.Response, err error) (*http.Response, error) {
+			return resp, err
+		}
+	}
+
+	b := &idleBody{client: client, request: r, watch: watch}
+	watched := b.connect(r)
+	return watched, func(resp *http.Response, err error) (*http.Response, error) {
+		if atomic.LoadInt32(b.expired) != 0 {
+			closeBody(resp)
+			b.cancel()
+			return nil, ErrIdleTimeout
+		}
+		if err != nil || resp.Body == nil {
+			b.timer.Stop()
+			b.cancel()
+			return resp, err
+		}
+		b.body = resp.Body
+		resp.Body = b
+		return resp, nil
+	}
+}
+
+// idleBody is the response body aborting or reconnecting the stream when it goes idle
+type idleBody struct {
+	client   *http.Client
+	request  *http.Request
+	watch    idleWatch
+	received int64
+
+	body    io.ReadCloser
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	expired *int32
+}
+
+// connect prepares the request for the next connection arming the idle timer
+func (b *idleBody) connect(r *http.Request) *http.Request {
+	ctx, cancel := context.WithCancel(r.Context())
+	expired := new(int32)
+	b.cancel, b.expired = cancel, expired
+	b.timer = time.AfterFunc(b.watch.timeout, func() {
+		atomic.StoreInt32(expired, 1)
+		cancel()
+	})
+	return r.WithContext(ctx)
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	for {
+		n, err := b.body.Read(p)
+		b.received += int64(n)
+		if n > 0 {
+			b.timer.Reset(b.watch.timeout)
+		}
+		if err == io.EOF {
+			b.timer.Stop()
+		}
+		if err == nil || err == io.EOF || atomic.LoadInt32(b.expired) == 0 {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+		if b.watch.reconnect == nil {
+			return 0, ErrIdleTimeout
+		}
+		if err := b.reconnect(); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// reconnect replaces the idle connection with the one made by the reconnect request
+func (b *idleBody) reconnect() error {
+	b.body.Close()
+	b.cancel()
+	next, err := b.watch.reconnect(b.request, b.received)
+	if err != nil {
+		return err
+	}
+	// the reconnect request is watched by this body rather than by its own one
+	next = b.connect(withValue(next, keyIdleTimeout, idleWatch{}))
+	resp, err := roundTrip(b.client, next)
+	if atomic.LoadInt32(b.expired) != 0 {
+		err = ErrIdleTimeout
+	}
+	if err != nil {
+		closeBody(resp)
+		b.timer.Stop()
+		b.cancel()
+		return err
+	}
+	b.body = resp.Body
+	if b.body == nil {
+		b.body = http.NoBody
+	}
+	return nil
+}
+
+func (b *idleBody) Close() error {
+	b.timer.Stop()
+	defer b.cancel()
+	return b.body.Close()
+}
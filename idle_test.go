@@ -0,0 +1,60 @@
+package reqstrategy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_WithIdleTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Received") != "" {
+			w.Write([]byte("-resumed"))
+			return
+		}
+		for i := 0; i < 5; i++ {
+			w.Write([]byte{'a' + byte(i)})
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+		if r.URL.Path == "/dead" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}))
+	defer server.Close()
+
+	read := func(path string, reconnect func(r *http.Request, received int64) (*http.Request, error)) (string, error) {
+		r, _ := http.NewRequest("GET", server.URL+path, nil)
+		resp, err := Do(server.Client(), WithIdleTimeout(r, 50*time.Millisecond, reconnect))
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	if body, err := read("/alive", nil); err != nil || body != "abcde" {
+		t.Fatalf("expected slow stream to complete, got %q %v", body, err)
+	}
+	if body, err := read("/dead", nil); err != ErrIdleTimeout || body != "abcde" {
+		t.Fatalf("expected ErrIdleTimeout after the data, got %q %v", body, err)
+	}
+
+	var received int64
+	body, err := read("/dead", func(r *http.Request, n int64) (*http.Request, error) {
+		received = n
+		next, _ := http.NewRequest("GET", r.URL.String(), nil)
+		next.Header.Set("X-Received", strconv.FormatInt(n, 10))
+		return next, nil
+	})
+	if err != nil || body != "abcde-resumed" || received != 5 {
+		t.Fatalf("expected stream to be resumed after 5 bytes, got %q after %d: %v", body, received, err)
+	}
+}
@@ -0,0 +1,52 @@
+package reqstrategy
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Jitter randomizes waits between retries so clients which failed at the same time do not retry in lockstep,
+// see WithJitter
+type Jitter int
+
+const (
+	// JitterNone waits exactly as the intervals or the backoff tell
+	JitterNone Jitter = iota
+	// JitterFull waits a random duration up to the interval
+	JitterFull
+	// JitterEqual waits half of the interval plus a random duration up to the other half
+	JitterEqual
+	// JitterDecorrelated waits a random duration between the interval and three times the previous wait,
+	// but not longer than three times the interval
+	JitterDecorrelated
+)
+
+// WithJitter makes retries of the request wait randomized intervals, it applies to both intervals and
+// backoffs, see Retry and RetryBackoff
+func WithJitter(r *http.Request, j Jitter) *http.Request {
+	return withValue(r, keyJitter, j)
+}
+
+// apply randomizes the wait, prev is the previous wait of the same retry loop, zero before the first one
+func (j Jitter) apply(wait, prev time.Duration) time.Duration {
+	if wait <= 0 {
+		return wait
+	}
+	switch j {
+	case JitterFull:
+		return time.Duration(rand.Int63n(int64(wait) + 1))
+	case JitterEqual:
+		return wait/2 + time.Duration(rand.Int63n(int64(wait-wait/2)+1))
+	case JitterDecorrelated:
+		upper := 3 * prev
+		if upper > 3*wait {
+			upper = 3 * wait
+		}
+		if upper <= wait {
+			return wait
+		}
+		return wait + time.Duration(rand.Int63n(int64(upper-wait)+1))
+	}
+	return wait
+}
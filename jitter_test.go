@@ -0,0 +1,48 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy/reqstrategytest"
+)
+
+func Test_Jitter(t *testing.T) {
+	wait := 100 * time.Millisecond
+	cases := map[Jitter][2]time.Duration{
+		JitterNone:         {wait, wait},
+		JitterFull:         {0, wait},
+		JitterEqual:        {wait / 2, wait},
+		JitterDecorrelated: {wait, 3 * wait},
+	}
+	for j, bounds := range cases {
+		for i := 0; i < 100; i++ {
+			if actual := j.apply(wait, 10*wait); actual < bounds[0] || actual > bounds[1] {
+				t.Fatalf("jitter %d: expected wait between %s and %s, got %s", j, bounds[0], bounds[1], actual)
+			}
+		}
+	}
+	if actual := JitterDecorrelated.apply(wait, 0); actual != wait {
+		t.Fatalf("expected the first decorrelated wait to be the interval, got %s", actual)
+	}
+}
+
+func Test_WithJitter(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 503}, nil
+	})
+	clock := reqstrategytest.NewVirtualClock(time.Now())
+	req := WithJitter(WithClock(WithStatusRequired(newRequest(t), 200), clock), JitterFull)
+
+	var total time.Duration
+	for i := 0; i < 10; i++ {
+		before := clock.Now()
+		Retry(client, req, time.Second, time.Second, time.Second)
+		total += clock.Now().Sub(before)
+	}
+	if total >= 30*time.Second {
+		t.Fatalf("expected jittered waits shorter than intervals, got %s in total", total)
+	}
+
+}
@@ -0,0 +1,87 @@
+package reqstrategy
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type keepAlive struct {
+	interval time.Duration
+	path     string
+}
+
+// WithKeepAlive makes the long-lived response stream, e.g. long poll or SSE, keep the session alive: whenever
+// no bytes of the response body arrive for interval, HEAD request to the path on the same host is sent with
+// the same client, so NAT and load balancer idle timeouts do not silently kill the session. It works best
+// with HTTP/2, where pings share the connection with the stream. Ping outcomes are ignored. Pings stop once
+// the body is read to the end or closed
+func WithKeepAlive(r *http.Request, interval time.Duration, path string) *http.Request {
+	return withValue(r, keyKeepAlive, keepAlive{interval, path})
+}
+
+// startKeepAlive starts pinging the host of the request while its response body is quiet
+func startKeepAlive(client *http.Client, r *http.Request, resp *http.Response) {
+	k, _ := r.Context().Value(keyKeepAlive).(keepAlive)
+	if k.interval <= 0 || resp == nil || resp.Body == nil {
+		return
+	}
+	target := *r.URL
+	target.Path, target.RawPath, target.RawQuery = k.path, "", ""
+
+	b := &keepAliveBody{ReadCloser: resp.Body, interval: k.interval}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.timer = time.AfterFunc(k.interval, func() {
+		ping, err := http.NewRequest("HEAD", target.String(), nil)
+		if err != nil {
+			return
+		}
+		if resp, err := client.Do(ping.WithContext(r.Context())); err == nil {
+			resp.Body.Close()
+		}
+		b.reset()
+	})
+	resp.Body = b
+}
+
+// keepAliveBody postpones pings while the data keeps coming
+type keepAliveBody struct {
+	io.ReadCloser
+	interval time.Duration
+
+	mu      sync.Mutex
+	timer   *time.Timer
+	stopped bool
+}
+
+func (b *keepAliveBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.stop()
+	} else if n > 0 {
+		b.reset()
+	}
+	return n, err
+}
+
+func (b *keepAliveBody) Close() error {
+	b.stop()
+	return b.ReadCloser.Close()
+}
+
+func (b *keepAliveBody) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.stopped {
+		b.timer.Reset(b.interval)
+	}
+}
+
+func (b *keepAliveBody) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopped = true
+	b.timer.Stop()
+}
@@ -0,0 +1,55 @@
+package reqstrategy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_WithKeepAlive(t *testing.T) {
+	var pings int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "HEAD" && r.URL.Path == "/health" {
+			atomic.AddInt32(&pings, 1)
+			return
+		}
+		w.Write([]byte("a"))
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/quiet" {
+			time.Sleep(200 * time.Millisecond)
+		} else {
+			for i := 0; i < 10; i++ {
+				time.Sleep(10 * time.Millisecond)
+				w.Write([]byte("a"))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer server.Close()
+
+	read := func(path string) int32 {
+		atomic.StoreInt32(&pings, 0)
+		r, _ := http.NewRequest("GET", server.URL+path, nil)
+		resp, err := Do(server.Client(), WithKeepAlive(r, 50*time.Millisecond, "/health"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return atomic.LoadInt32(&pings)
+	}
+
+	if n := read("/quiet"); n < 2 {
+		t.Fatalf("expected quiet stream to be pinged, got %d pings", n)
+	}
+	if n := read("/busy"); n != 0 {
+		t.Fatalf("expected busy stream not to be pinged, got %d pings", n)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&pings); n != 0 {
+		t.Fatalf("expected pings to stop once the body is read, got %d", n)
+	}
+}
@@ -0,0 +1,83 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"sort"
+)
+
+// KeyedErrors is returned by SomeMap when all requests failed, it holds their errors by name
+type KeyedErrors map[string]error
+
+func (e KeyedErrors) Error() string {
+	return "all requests failed"
+}
+
+// Unwrap returns errors of the requests
+func (e KeyedErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, name := range sortedKeys(e) {
+		errs = append(errs, e[name])
+	}
+	return errs
+}
+
+// AllMap is All for requests named by the caller, responses are returned by the same names.
+// Requests are sent in the order of their names
+func AllMap(client *http.Client, requests map[string]*http.Request) (map[string]*http.Response, error) {
+	names, list := namedRequests(requests)
+	responses, err := All(client, list...)
+	if err != nil {
+		return nil, err
+	}
+	return namedResponses(names, responses), nil
+}
+
+// SomeMap is Some for requests named by the caller, responses of successful requests are returned by
+// the same names, failed ones are left out. KeyedErrors is returned only if all requests failed
+func SomeMap(client *http.Client, requests map[string]*http.Request) (map[string]*http.Response, error) {
+	names, list := namedRequests(requests)
+	responses, err := Some(client, list...)
+	if errs, ok := err.(Errors); ok {
+		keyed := make(KeyedErrors, len(errs))
+		for i, err := range errs {
+			keyed[names[i]] = err
+		}
+		return nil, keyed
+	}
+	if err != nil {
+		return nil, err
+	}
+	return namedResponses(names, responses), nil
+}
+
+func namedRequests(requests map[string]*http.Request) ([]string, []*http.Request) {
+	names := make([]string, 0, len(requests))
+	for name := range requests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]*http.Request, len(names))
+	for i, name := range names {
+		list[i] = requests[name]
+	}
+	return names, list
+}
+
+func namedResponses(names []string, responses []*http.Response) map[string]*http.Response {
+	named := make(map[string]*http.Response, len(names))
+	for i, resp := range responses {
+		if resp != nil {
+			named[names[i]] = resp
+		}
+	}
+	return named
+}
+
+func sortedKeys(m map[string]error) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
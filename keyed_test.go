@@ -0,0 +1,55 @@
+package reqstrategy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func Test_AllMap(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	responses, err := AllMap(client, map[string]*http.Request{
+		"user":   newRequest(t, "user"),
+		"orders": newRequest(t, "orders"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(responses) != 2 || responses["user"].Request.URL.Path != "/user" || responses["orders"].Request.URL.Path != "/orders" {
+		t.Fatalf("expected responses by name, got %v", responses)
+	}
+}
+
+func Test_SomeMap(t *testing.T) {
+	failed := errors.New("failed")
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/orders" {
+			return nil, failed
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	responses, err := SomeMap(client, map[string]*http.Request{
+		"user":   newRequest(t, "user"),
+		"orders": newRequest(t, "orders"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := responses["orders"]; ok || responses["user"] == nil {
+		t.Fatalf(`expected only the response of "user", got %v`, responses)
+	}
+
+	_, err = SomeMap(client, map[string]*http.Request{"orders": newRequest(t, "orders")})
+	var errs KeyedErrors
+	if !errors.As(err, &errs) || !errors.Is(errs["orders"], failed) {
+		t.Fatalf(`expected KeyedErrors with "orders" failure, got %v`, err)
+	}
+	if fmt.Sprint(errs.Unwrap()) != fmt.Sprint([]error{errs["orders"]}) {
+		t.Fatalf("expected unwrapped errors, got %v", errs.Unwrap())
+	}
+}
@@ -0,0 +1,73 @@
+package reqstrategy
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrDisabled is returned when the request is not sent because its host or tag is turned off, see KillSwitch
+var ErrDisabled = errors.New("request disabled")
+
+// KillSwitch is a runtime store of hosts and tags (see WithTag) which must not be called, e.g. when
+// a partner API must not be called during an incident. It is safe for concurrent use
+type KillSwitch struct {
+	mu    sync.RWMutex
+	hosts map[string]bool
+	tags  map[string]bool
+}
+
+// NewKillSwitch creates KillSwitch with everything enabled
+func NewKillSwitch() *KillSwitch {
+	return &KillSwitch{hosts: make(map[string]bool), tags: make(map[string]bool)}
+}
+
+// WithKillSwitch makes the request fail with ErrDisabled instead of being sent while its host or tag is disabled
+func WithKillSwitch(r *http.Request, k *KillSwitch) *http.Request {
+	return withValue(r, keyKillSwitch, k)
+}
+
+// DisableHost turns off requests to the host
+func (k *KillSwitch) DisableHost(host string) {
+	k.set(k.hosts, host, true)
+}
+
+// EnableHost turns requests to the host back on
+func (k *KillSwitch) EnableHost(host string) {
+	k.set(k.hosts, host, false)
+}
+
+// DisableTag turns off requests marked with the tag
+func (k *KillSwitch) DisableTag(tag string) {
+	k.set(k.tags, tag, true)
+}
+
+// EnableTag turns requests marked with the tag back on
+func (k *KillSwitch) EnableTag(tag string) {
+	k.set(k.tags, tag, false)
+}
+
+// Allowed reports whether the request may be sent
+func (k *KillSwitch) Allowed(r *http.Request) bool {
+	tag, _ := r.Context().Value(keyTag).(string)
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return !k.hosts[r.URL.Host] && !k.tags[tag]
+}
+
+func (k *KillSwitch) set(m map[string]bool, name string, disabled bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if disabled {
+		m[name] = true
+	} else {
+		delete(m, name)
+	}
+}
+
+func allowed(r *http.Request) error {
+	if k, ok := r.Context().Value(keyKillSwitch).(*KillSwitch); ok && !k.Allowed(r) {
+		return ErrDisabled
+	}
+	return nil
+}
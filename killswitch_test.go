@@ -0,0 +1,34 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_KillSwitch(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	k := NewKillSwitch()
+	partner := WithKillSwitch(WithTag(newRequest(t), "partner"), k)
+	other := WithKillSwitch(newRequest(t), k)
+
+	k.DisableTag("partner")
+	if _, err := Do(client, partner); err != ErrDisabled {
+		t.Fatalf("expected ErrDisabled, got %v", err)
+	}
+	if _, err := Do(client, other); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	k.EnableTag("partner")
+	k.DisableHost("localhost")
+	if _, err := Do(client, other); err != ErrDisabled {
+		t.Fatalf("expected ErrDisabled, got %v", err)
+	}
+	k.EnableHost("localhost")
+	if _, err := Do(client, partner); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
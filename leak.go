@@ -0,0 +1,79 @@
+package reqstrategy
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// ConsumeJSON decodes the response body into v, the body is read to the end and closed either way
+func ConsumeJSON(resp *http.Response, v interface{}) error {
+	defer Discard(resp)
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// Discard reads the response body to the end and closes it, so the connection can be reused.
+// It is safe to call with <nil> response or body
+func Discard(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// Leak describes the response which body was never closed, see WithLeakTracking
+type Leak struct {
+	CallID string
+	Method string
+	URL    string
+	Status int
+	// Stack is the stack trace of the goroutine which received the response
+	Stack []byte
+}
+
+// WithLeakTracking makes responses to the request tracked until their bodies are closed, report is called
+// for every response which body was garbage collected without being closed. Tracking captures the stack
+// trace of every response, so it is meant for debugging connection leaks rather than production use.
+// Reports are only made once the garbage collector runs
+func WithLeakTracking(r *http.Request, report func(Leak)) *http.Request {
+	return withValue(r, keyLeaks, report)
+}
+
+// trackLeak makes the response reported if its body is never closed, see WithLeakTracking
+func trackLeak(id string, r *http.Request, resp *http.Response) {
+	report, _ := r.Context().Value(keyLeaks).(func(Leak))
+	if report == nil || resp == nil || resp.Body == nil || resp.Body == http.NoBody {
+		return
+	}
+	body := &leakBody{ReadCloser: resp.Body}
+	leak := Leak{CallID: id, Method: r.Method, URL: redactURL(r.URL), Status: resp.StatusCode, Stack: debug.Stack()}
+	runtime.SetFinalizer(body, func(b *leakBody) {
+		if !b.isClosed() {
+			report(leak)
+		}
+	})
+	resp.Body = body
+}
+
+type leakBody struct {
+	io.ReadCloser
+	mu     sync.Mutex
+	closed bool
+}
+
+func (b *leakBody) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	return b.ReadCloser.Close()
+}
+
+func (b *leakBody) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
@@ -0,0 +1,65 @@
+package reqstrategy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_ConsumeJSON(t *testing.T) {
+	var closed int32
+	resp := &http.Response{Body: &trackedBody{strings.NewReader(`{"name": "reqstrategy"} trailing`), &closed}}
+
+	var v struct{ Name string }
+	if err := ConsumeJSON(resp, &v); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if v.Name != "reqstrategy" || closed != 1 {
+		t.Fatalf("expected decoded value and closed body, got %+v, closed %d times", v, closed)
+	}
+
+	closed = 0
+	if err := ConsumeJSON(&http.Response{Body: &trackedBody{strings.NewReader(`{`), &closed}}, &v); err == nil || closed != 1 {
+		t.Fatalf("expected the body closed after decoding error")
+	}
+	Discard(nil)
+}
+
+func Test_WithLeakTracking(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("data"))}, nil
+	})
+	leaks := make(chan Leak, 2)
+	req := WithLeakTracking(newRequest(t, "leaked"), func(l Leak) {
+		leaks <- l
+	})
+
+	func() {
+		resp, err := Do(client, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		Discard(resp)
+		Do(client, req)
+	}()
+
+	for i := 0; i < 50 && len(leaks) == 0; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case l := <-leaks:
+		if l.URL != "http://localhost/leaked" || l.Status != 200 || len(l.Stack) == 0 {
+			t.Fatalf("unexpected leak reported: %+v", l)
+		}
+	default:
+		t.Fatalf("expected the leaked response reported")
+	}
+	runtime.GC()
+	if len(leaks) != 0 {
+		t.Fatalf("expected only one leak, got %d more", len(leaks))
+	}
+}
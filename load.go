@@ -0,0 +1,100 @@
+package reqstrategy
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Rate returns the number of requests per second to be started at the time elapsed since the load started
+type Rate func(elapsed time.Duration) float64
+
+// ConstantRate starts requests at the same rate all the time
+func ConstantRate(perSecond float64) Rate {
+	return func(time.Duration) float64 {
+		return perSecond
+	}
+}
+
+// LinearRate ramps the rate from one value to another over the period, keeping the final rate afterwards
+func LinearRate(from, to float64, over time.Duration) Rate {
+	return func(elapsed time.Duration) float64 {
+		if elapsed >= over || over <= 0 {
+			return to
+		}
+		return from + (to-from)*float64(elapsed)/float64(over)
+	}
+}
+
+// LoadReport summarises the load generated by Generate
+type LoadReport struct {
+	Requests int
+	Failures int
+	// Errors counts failures by error message
+	Errors   map[string]int
+	Duration time.Duration
+	// Latency aggregates request latencies, P50, P99, P999 and Max are taken from it
+	Latency *Histogram
+	Mean    time.Duration
+	P50     time.Duration
+	P99     time.Duration
+	P999    time.Duration
+	Max     time.Duration
+}
+
+// Generate produces open-loop load starting requests made by factory at the given rate for the duration,
+// regardless of how long previous requests take. Requests are sent with Do, so validators define what counts
+// as success. Response bodies are read and closed. Generate returns once all started requests are complete
+func Generate(client *http.Client, factory func() *http.Request, rate Rate, duration time.Duration) LoadReport {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	report := LoadReport{Errors: make(map[string]int), Latency: NewHistogram()}
+
+	start := time.Now()
+	next := start
+	for {
+		elapsed := next.Sub(start)
+		if elapsed >= duration {
+			break
+		}
+		perSecond := rate(elapsed)
+		if perSecond <= 0 {
+			next = next.Add(10 * time.Millisecond)
+			continue
+		}
+		if wait := time.Until(next); wait > 0 {
+			time.Sleep(wait)
+		}
+		next = next.Add(time.Duration(float64(time.Second) / perSecond))
+
+		wg.Add(1)
+		spawn(func() {
+			defer wg.Done()
+			began := time.Now()
+			resp, err := Do(client, factory())
+			if resp != nil && resp.Body != nil {
+				io.Copy(ioutil.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			report.Latency.Observe(time.Since(began))
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Requests++
+			if err != nil {
+				report.Failures++
+				report.Errors[err.Error()]++
+			}
+		})
+	}
+	wg.Wait()
+	report.Duration = time.Since(start)
+	report.Mean = report.Latency.Mean()
+	report.P50 = report.Latency.Quantile(0.5)
+	report.P99 = report.Latency.Quantile(0.99)
+	report.P999 = report.Latency.Quantile(0.999)
+	report.Max = report.Latency.Max()
+	return report
+}
@@ -0,0 +1,39 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Generate(t *testing.T) {
+	var calls int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&calls, 1)%4 == 0 {
+			return &http.Response{StatusCode: 500, Request: r}, nil
+		}
+		return &http.Response{StatusCode: 200, Request: r}, nil
+	})
+
+	report := Generate(client, func() *http.Request {
+		return WithStatusRequired(newRequest(t), 200)
+	}, ConstantRate(400), 100*time.Millisecond)
+
+	if report.Requests != 40 {
+		t.Fatalf("expected 40 requests, got %d", report.Requests)
+	}
+	if report.Failures != 10 || len(report.Errors) != 1 {
+		t.Fatalf("expected 10 failures of the same kind, got %d: %v", report.Failures, report.Errors)
+	}
+	if report.Duration < 90*time.Millisecond {
+		t.Fatalf("expected load to last for the duration, got %s", report.Duration)
+	}
+}
+
+func Test_LinearRate(t *testing.T) {
+	rate := LinearRate(10, 20, time.Second)
+	if rate(0) != 10 || rate(500*time.Millisecond) != 15 || rate(2*time.Second) != 20 {
+		t.Fatalf("unexpected rates %v, %v, %v", rate(0), rate(500*time.Millisecond), rate(2*time.Second))
+	}
+}
@@ -0,0 +1,67 @@
+package reqstrategy
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Locker coordinates deliveries of the same request across processes, so replicas of the service sharing
+// persisted requests do not retry the same one simultaneously, see Queue.SetLocker. Requests are identified
+// by the key, e.g. the IdempotencyKeyHeader value. Distributed implementations should make the lock expire
+// after ttl in case the owner dies, e.g. SET key token NX PX ttl in Redis or the key attached to the lease in etcd,
+// and release it only if it still holds the token, e.g. with the compare-and-delete script or transaction
+type Locker interface {
+	// Acquire takes the lock of the key for ttl returning the token identifying the owner,
+	// ok is false if the lock is held by another owner
+	Acquire(ctx context.Context, key string, ttl time.Duration) (token string, ok bool, err error)
+	// Release gives the lock up, lock taken over by another owner after it expired is kept
+	Release(ctx context.Context, key, token string) error
+}
+
+// MemoryLocker is the Locker for queues of the same process and tests. It is safe for concurrent use
+type MemoryLocker struct {
+	mu    sync.Mutex
+	clock Clock
+	locks map[string]memoryLock
+}
+
+type memoryLock struct {
+	token  string
+	expiry time.Time
+}
+
+// NewMemoryLocker creates MemoryLocker with no locks held
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{clock: realClock{}, locks: make(map[string]memoryLock)}
+}
+
+// SetClock sets the time source lock expiry is checked with
+func (l *MemoryLocker) SetClock(c Clock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock = c
+}
+
+// Acquire takes the lock of the key unless it is held and not expired yet
+func (l *MemoryLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.clock.Now()
+	if lock, ok := l.locks[key]; ok && now.Before(lock.expiry) {
+		return "", false, nil
+	}
+	token := NewCallID()
+	l.locks[key] = memoryLock{token, now.Add(ttl)}
+	return token, true, nil
+}
+
+// Release gives the lock of the key up if it is still held with the token
+func (l *MemoryLocker) Release(ctx context.Context, key, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lock, ok := l.locks[key]; ok && lock.token == token {
+		delete(l.locks, key)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package reqstrategy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy/reqstrategytest"
+)
+
+func Test_MemoryLocker(t *testing.T) {
+	ctx := context.Background()
+	clock := reqstrategytest.NewVirtualClock(time.Now())
+	locker := NewMemoryLocker()
+	locker.SetClock(clock)
+
+	first, ok, _ := locker.Acquire(ctx, "key", time.Minute)
+	if !ok {
+		t.Fatalf("expected the lock acquired")
+	}
+	if _, ok, _ := locker.Acquire(ctx, "key", time.Minute); ok {
+		t.Fatalf("expected the lock held")
+	}
+	if _, ok, _ := locker.Acquire(ctx, "other", time.Minute); !ok {
+		t.Fatalf("expected locks of other keys independent")
+	}
+
+	<-clock.After(time.Minute)
+	second, ok, _ := locker.Acquire(ctx, "key", time.Minute)
+	if !ok {
+		t.Fatalf("expected the expired lock taken over")
+	}
+	locker.Release(ctx, "key", first)
+	if _, ok, _ := locker.Acquire(ctx, "key", time.Minute); ok {
+		t.Fatalf("expected the lock taken over kept on release with the old token")
+	}
+	locker.Release(ctx, "key", second)
+	if _, ok, _ := locker.Acquire(ctx, "key", time.Minute); !ok {
+		t.Fatalf("expected the released lock acquired")
+	}
+}
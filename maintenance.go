@@ -0,0 +1,119 @@
+package reqstrategy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrInMaintenance is reported for requests not sent because their host is in maintenance, see WithMaintenance
+var ErrInMaintenance = errors.New("host in maintenance")
+
+// Window is a daily period in UTC, e.g. 02:00-03:00, see ParseWindow. Window may span midnight
+type Window struct {
+	// Start and End are offsets since midnight UTC
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseWindow parses the window from "HH:MM" start and end times in UTC
+func ParseWindow(start, end string) (Window, error) {
+	var w Window
+	for _, t := range []struct {
+		value string
+		to    *time.Duration
+	}{{start, &w.Start}, {end, &w.End}} {
+		parsed, err := time.Parse("15:04", t.value)
+		if err != nil {
+			return Window{}, fmt.Errorf("invalid window time %q", t.value)
+		}
+		*t.to = time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute
+	}
+	return w, nil
+}
+
+// Contains reports whether the time falls into the window
+func (w Window) Contains(t time.Time) bool {
+	t = t.UTC()
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	if w.Start <= w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// Maintenance is a runtime store of hosts maintenance windows. Strategies choosing between endpoints,
+// like Race and Fallback, avoid hosts in maintenance unless all of them are. It is safe for concurrent use
+type Maintenance struct {
+	mu      sync.RWMutex
+	windows map[string][]Window
+}
+
+// NewMaintenance creates Maintenance with no windows
+func NewMaintenance() *Maintenance {
+	return &Maintenance{windows: make(map[string][]Window)}
+}
+
+// WithMaintenance makes strategies avoid the request while its host is in maintenance
+func WithMaintenance(r *http.Request, m *Maintenance) *http.Request {
+	return withValue(r, keyMaintenance, m)
+}
+
+// Set replaces maintenance windows of the host, no windows clear the schedule
+func (m *Maintenance) Set(host string, windows ...Window) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(windows) == 0 {
+		delete(m.windows, host)
+		return
+	}
+	m.windows[host] = append([]Window(nil), windows...)
+}
+
+// InMaintenance reports whether the host is in maintenance at the time
+func (m *Maintenance) InMaintenance(host string, t time.Time) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, w := range m.windows[host] {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// avoidMaintenance drops the requests to hosts in maintenance returning positions of the rest in requests,
+// requests are returned as is if all of them are in maintenance
+func avoidMaintenance(requests []*http.Request) ([]*http.Request, []int) {
+	var available []*http.Request
+	var positions []int
+	for i, r := range requests {
+		m, ok := r.Context().Value(keyMaintenance).(*Maintenance)
+		if !ok || !m.InMaintenance(r.URL.Host, clockOf(r).Now()) {
+			available = append(available, r)
+			positions = append(positions, i)
+		}
+	}
+	if len(available) == 0 {
+		positions = make([]int, len(requests))
+		for i := range positions {
+			positions[i] = i
+		}
+		return requests, positions
+	}
+	return available, positions
+}
+
+// skippedErrors returns Errors of n requests with ErrInMaintenance for the ones avoidMaintenance dropped
+func skippedErrors(n int, positions []int) Errors {
+	errs := make(Errors, n)
+	for i := range errs {
+		errs[i] = ErrInMaintenance
+	}
+	for _, i := range positions {
+		errs[i] = nil
+	}
+	return errs
+}
@@ -0,0 +1,107 @@
+package reqstrategy
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_Window_Contains(t *testing.T) {
+	night, err := ParseWindow("23:30", "01:00")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	at := func(clock string) time.Time {
+		t, _ := time.Parse("15:04", clock)
+		return t
+	}
+	if !night.Contains(at("23:45")) || !night.Contains(at("00:30")) || night.Contains(at("01:00")) || night.Contains(at("12:00")) {
+		t.Fatal("unexpected window boundaries")
+	}
+	if _, err := ParseWindow("2am", "3am"); err == nil {
+		t.Fatal("expected invalid window error")
+	}
+}
+
+func Test_Maintenance(t *testing.T) {
+	var hosts []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		hosts = append(hosts, r.URL.Host)
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	now := time.Now().UTC()
+	window := Window{
+		Start: time.Duration(now.Hour()) * time.Hour,
+		End:   time.Duration(now.Hour()+1) * time.Hour,
+	}
+	maintenance := NewMaintenance()
+	maintenance.Set("primary", window)
+
+	request := func(host string) *http.Request {
+		r, _ := http.NewRequest("GET", "http://"+host+"/", nil)
+		return WithMaintenance(r, maintenance)
+	}
+
+	resp, err := Fallback(client, request("primary"), request("backup"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.Request.URL.Host != "backup" || len(hosts) != 1 {
+		t.Fatalf("expected primary in maintenance to be skipped, got %v", hosts)
+	}
+
+	hosts = nil
+	maintenance.Set("primary")
+	Fallback(client, request("primary"), request("backup"))
+	if len(hosts) != 1 || hosts[0] != "primary" {
+		t.Fatalf("expected primary to be used after the window is cleared, got %v", hosts)
+	}
+
+	hosts = nil
+	maintenance.Set("primary", window)
+	maintenance.Set("backup", window)
+	Fallback(client, request("primary"), request("backup"))
+	if len(hosts) != 1 || hosts[0] != "primary" {
+		t.Fatalf("expected requests to be sent when all hosts are in maintenance, got %v", hosts)
+	}
+}
+
+func Test_Maintenance_Race(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "broken" {
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	now := time.Now().UTC()
+	maintenance := NewMaintenance()
+	maintenance.Set("primary", Window{
+		Start: time.Duration(now.Hour()) * time.Hour,
+		End:   time.Duration(now.Hour()+1) * time.Hour,
+	})
+	request := func(host string) *http.Request {
+		r, _ := http.NewRequest("GET", "http://"+host+"/", nil)
+		return WithStatusRequired(WithMaintenance(r, maintenance), 200)
+	}
+
+	losers := make(chan int, 3)
+	resp, err := RaceVerbose(client, func(i int, err error) {
+		if (i == 0) != errors.Is(err, ErrInMaintenance) {
+			t.Errorf("unexpected error of loser #%d: %v", i, err)
+		}
+		losers <- i
+	}, request("primary"), request("broken"), request("backup"))
+	if err != nil || resp.Request.URL.Host != "backup" {
+		t.Fatalf("expected backup to win, got %v", err)
+	}
+	if a, b := <-losers, <-losers; a+b != 1 {
+		t.Fatalf("expected losers #0 and #1 reported, got #%d and #%d", a, b)
+	}
+
+	_, err = Race(client, request("primary"), request("broken"), request("broken"))
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 3 || errs[0] != ErrInMaintenance || errs[1] == nil || errs[1] == ErrInMaintenance {
+		t.Fatalf("expected errors in the order of requests, got %v", errs)
+	}
+}
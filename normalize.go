@@ -0,0 +1,90 @@
+package reqstrategy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// VolatileHeaders are response headers expected to differ between backends, NormalizeHeaders ignores them
+var VolatileHeaders = []string{"Date", "Age", "Expires", "Set-Cookie", "Server", "Server-Timing", "Via", "X-Request-Id", CallIDHeader}
+
+// NormalizeJSON returns the normalizer canonicalizing JSON bodies, so responses differing in key order
+// or formatting agree. Listed fields are removed before comparison, nested fields are addressed with dots,
+// e.g. "meta.generatedAt", the field is removed from every object of the array it belongs to
+func NormalizeJSON(ignore ...string) Normalizer {
+	return func(r *http.Response) ([]byte, error) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		for _, field := range ignore {
+			removeField(value, strings.Split(field, "."))
+		}
+		return json.Marshal(value)
+	}
+}
+
+// NormalizeHeaders returns the normalizer prefixing the representation made by body (the raw body if <nil>)
+// with the status and headers, except VolatileHeaders and listed ones
+func NormalizeHeaders(body Normalizer, ignore ...string) Normalizer {
+	skip := make(map[string]bool)
+	for _, name := range append(VolatileHeaders[:len(VolatileHeaders):len(VolatileHeaders)], ignore...) {
+		skip[http.CanonicalHeaderKey(name)] = true
+	}
+	return func(r *http.Response) ([]byte, error) {
+		var names []string
+		for name := range r.Header {
+			if !skip[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		var b bytes.Buffer
+		b.WriteString(r.Status)
+		b.WriteString("\n")
+		for _, name := range names {
+			b.WriteString(name + ": " + strings.Join(r.Header[name], ", ") + "\n")
+		}
+		b.WriteString("\n")
+
+		var data []byte
+		var err error
+		if body == nil {
+			data, err = ioutil.ReadAll(r.Body)
+		} else {
+			data, err = body(r)
+		}
+		if err != nil {
+			return nil, err
+		}
+		b.Write(data)
+		return b.Bytes(), nil
+	}
+}
+
+// removeField removes the field at path from objects of the decoded JSON value
+func removeField(value interface{}, path []string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(v, path[0])
+			return
+		}
+		removeField(v[path[0]], path[1:])
+	case []interface{}:
+		for _, item := range v {
+			removeField(item, path)
+		}
+	}
+}
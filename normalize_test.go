@@ -0,0 +1,43 @@
+package reqstrategy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func response(status string, header http.Header, body string) *http.Response {
+	return &http.Response{Status: status, Header: header, Body: ioutil.NopCloser(strings.NewReader(body))}
+}
+
+func Test_NormalizeJSON(t *testing.T) {
+	normalize := NormalizeJSON("meta.generatedAt", "items.etag")
+	a, err := normalize(response("200 OK", nil, `{"items": [{"id": 1, "etag": "x"}], "meta": {"generatedAt": 1, "total": 1}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	b, err := normalize(response("200 OK", nil, `{"meta":{"total":1,"generatedAt":2},"items":[{"etag":"y","id":1}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(a) != string(b) || string(a) != `{"items":[{"id":1}],"meta":{"total":1}}` {
+		t.Fatalf("expected bodies to agree, got %s and %s", a, b)
+	}
+	if _, err := normalize(response("200 OK", nil, "<html>")); err == nil {
+		t.Fatal("expected invalid JSON error")
+	}
+}
+
+func Test_NormalizeHeaders(t *testing.T) {
+	normalize := NormalizeHeaders(NormalizeJSON(), "X-Backend")
+	a, _ := normalize(response("200 OK", http.Header{"Date": {"Mon"}, "X-Backend": {"a"}, "Content-Type": {"application/json"}}, `{"a": 1, "b": 2}`))
+	b, _ := normalize(response("200 OK", http.Header{"Date": {"Tue"}, "X-Backend": {"b"}, "Content-Type": {"application/json"}}, `{"b": 2, "a": 1}`))
+	if string(a) != string(b) {
+		t.Fatalf("expected responses to agree, got %q and %q", a, b)
+	}
+	c, _ := normalize(response("200 OK", http.Header{"Content-Type": {"text/plain"}}, `{"a": 1, "b": 2}`))
+	if string(a) == string(c) {
+		t.Fatal("expected different content types to disagree")
+	}
+}
@@ -0,0 +1,56 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/syavorsky/reqstrategy"
+)
+
+// DriftAnnotation is the response annotation holding the contract mismatch found by the drift check,
+// see reqstrategy.Annotation
+const DriftAnnotation = "openapi-drift"
+
+// Drift describes the response not matching the spec
+type Drift struct {
+	Operation *Operation
+	Response  *http.Response
+	Err       error
+}
+
+// WithDriftCheck checks responses against the operation without failing the call, so API drift can be
+// detected in production safely. Mismatches are annotated on the response under DriftAnnotation, making
+// them visible to hooks through Attempt.Response, and passed to report, if it is not <nil>
+func (op *Operation) WithDriftCheck(r *http.Request, report func(Drift)) *http.Request {
+	return reqstrategy.WithValidator(r, func(resp *http.Response) error {
+		if err := op.Validate(resp); err != nil {
+			reqstrategy.Annotate(resp, DriftAnnotation, err.Error())
+			if report != nil {
+				report(Drift{op, resp, err})
+			}
+		}
+		return nil
+	})
+}
+
+// WithDriftCheck adds the drift check of the operation the request belongs to, found by the call name
+// (see reqstrategy.WithCallName) or by method and path. Request is returned as is if there is no such operation
+func (s *Spec) WithDriftCheck(r *http.Request, report func(Drift)) *http.Request {
+	if op := s.match(r); op != nil {
+		return op.WithDriftCheck(r, report)
+	}
+	return r
+}
+
+func (s *Spec) match(r *http.Request) *Operation {
+	if name, ok := r.Context().Value(reqstrategy.CallNameKey).(string); ok {
+		if op := s.Operation(name); op != nil {
+			return op
+		}
+	}
+	for _, op := range s.operations {
+		if op.Rule().Match(r) {
+			return op
+		}
+	}
+	return nil
+}
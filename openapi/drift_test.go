@@ -0,0 +1,39 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/syavorsky/reqstrategy"
+)
+
+func Test_Spec_WithDriftCheck(t *testing.T) {
+	spec, err := Load([]byte(document))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var drifts []Drift
+	req, _ := http.NewRequest("GET", "http://localhost/users/1", nil)
+	req = spec.WithDriftCheck(req, func(d Drift) {
+		drifts = append(drifts, d)
+	})
+	resp, err := reqstrategy.Do(reply(200, "application/json", `{"id": 1}`), req)
+	if err != nil {
+		t.Fatalf("expected drift not to fail the call, got %s", err)
+	}
+	if len(drifts) != 1 || drifts[0].Operation.ID != "getUserProfile" {
+		t.Fatalf("expected drift of getUserProfile to be reported, got %v", drifts)
+	}
+	drift, ok := reqstrategy.Annotation(resp, DriftAnnotation)
+	if !ok || !strings.Contains(drift, `required property "name" is missing`) {
+		t.Fatalf("expected drift annotation, got %q", drift)
+	}
+
+	req, _ = http.NewRequest("GET", "http://localhost/users/1", nil)
+	resp, _ = reqstrategy.Do(reply(200, "application/json", `{"id": 1, "name": "joe"}`), spec.WithDriftCheck(req, nil))
+	if _, ok := reqstrategy.Annotation(resp, DriftAnnotation); ok {
+		t.Fatal("expected no drift for the valid response")
+	}
+}
@@ -0,0 +1,216 @@
+// Package openapi generates reqstrategy response validators from OpenAPI 3 specs in JSON format,
+// keeping client-side validation in sync with the contract
+//
+//	spec, err := openapi.Load(data)
+//	...
+//	runner := &reqstrategy.Runner{Client: client}
+//	spec.Register(runner)
+//	resp, err := runner.Execute(reqstrategy.WithCallName(req, "getUserProfile"))
+//
+// Responses are checked for documented status codes, content types and JSON body schemas.
+// Schemas support type, nullable, enum, required, properties, items and local $ref
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/syavorsky/reqstrategy"
+)
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Spec is the parsed OpenAPI document
+type Spec struct {
+	operations []*Operation
+	schemas    map[string]*schema
+}
+
+// Operation is a single API operation described by the spec
+type Operation struct {
+	// ID is the operationId, used as the call name, see reqstrategy.WithCallName
+	ID     string
+	Method string
+	// Path is the path template as written in the spec, e.g. /users/{id}
+	Path string
+
+	responses map[string]response
+	spec      *Spec
+}
+
+type response struct {
+	Content map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+}
+
+// Load parses the OpenAPI document in JSON format
+func Load(data []byte) (*Spec, error) {
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]*schema `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %s", err)
+	}
+
+	s := &Spec{schemas: doc.Components.Schemas}
+	for p, item := range doc.Paths {
+		for _, method := range methods {
+			raw, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op struct {
+				OperationID string              `json:"operationId"`
+				Responses   map[string]response `json:"responses"`
+			}
+			if err := json.Unmarshal(raw, &op); err != nil {
+				return nil, fmt.Errorf("invalid %s %s operation: %s", strings.ToUpper(method), p, err)
+			}
+			s.operations = append(s.operations, &Operation{
+				ID:        op.OperationID,
+				Method:    strings.ToUpper(method),
+				Path:      p,
+				responses: op.Responses,
+				spec:      s,
+			})
+		}
+	}
+	sort.Slice(s.operations, func(i, j int) bool {
+		a, b := s.operations[i], s.operations[j]
+		return a.Path < b.Path || a.Path == b.Path && a.Method < b.Method
+	})
+	return s, nil
+}
+
+// Operations returns all operations ordered by path and method
+func (s *Spec) Operations() []*Operation {
+	return append([]*Operation(nil), s.operations...)
+}
+
+// Operation returns the operation by its operationId, <nil> if there is no such operation
+func (s *Spec) Operation(id string) *Operation {
+	for _, op := range s.operations {
+		if op.ID == id {
+			return op
+		}
+	}
+	return nil
+}
+
+// Register sets the policy validating responses for every operation having operationId, see Runner.SetCallPolicy
+func (s *Spec) Register(runner *reqstrategy.Runner) {
+	for _, op := range s.operations {
+		if op.ID != "" {
+			runner.SetCallPolicy(op.ID, op.Policy())
+		}
+	}
+}
+
+// Policy returns the policy validating responses of the operation, it can be extended with retries etc
+func (op *Operation) Policy() reqstrategy.Policy {
+	return reqstrategy.Policy{
+		Methods:    []string{op.Method},
+		Validators: []func(*http.Response) error{op.Validate},
+	}
+}
+
+// Rule returns the rule matching requests of the operation by method and path, see Runner.Handle
+func (op *Operation) Rule() reqstrategy.Rule {
+	segments := strings.Split(op.Path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = "*"
+		}
+	}
+	return reqstrategy.Rule{Methods: []string{op.Method}, Path: strings.Join(segments, "/")}
+}
+
+// WithValidator adds the operation response validator to the request, see reqstrategy.WithValidator
+func (op *Operation) WithValidator(r *http.Request) *http.Request {
+	return reqstrategy.WithValidator(r, op.Validate)
+}
+
+// Validate checks the response status, content type and JSON body against the spec.
+// Body stays available for reading from memory after the check
+func (op *Operation) Validate(r *http.Response) error {
+	fail := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%s %s: %s", r.Request.Method, r.Request.URL, fmt.Sprintf(format, args...))
+	}
+
+	resp, ok := op.response(r.StatusCode)
+	if !ok {
+		return fail("response status %d is not documented for %s", r.StatusCode, op.name())
+	}
+	if len(resp.Content) == 0 {
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	content, ok := resp.Content[mediaType]
+	if !ok {
+		wildcard := mediaType
+		if i := strings.Index(mediaType, "/"); i >= 0 {
+			wildcard = mediaType[:i] + "/*"
+		}
+		if content, ok = resp.Content[wildcard]; !ok {
+			content, ok = resp.Content["*/*"]
+		}
+	}
+	if !ok {
+		return fail("unexpected content type %q", mediaType)
+	}
+	if content.Schema == nil || !strings.HasSuffix(mediaType, "json") {
+		return nil
+	}
+
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return fail("failed to read response body: %s", err)
+		}
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fail("invalid JSON body: %s", err)
+	}
+	if err := op.spec.validate(content.Schema, value, "body"); err != nil {
+		return fail("%s", err)
+	}
+	return nil
+}
+
+// response returns the response description for the status, falling back to ranges like 2XX and default
+func (op *Operation) response(status int) (response, bool) {
+	code := strconv.Itoa(status)
+	for _, key := range []string{code, code[:1] + "XX", "default"} {
+		if resp, ok := op.responses[key]; ok {
+			return resp, true
+		}
+		if resp, ok := op.responses[strings.ToLower(key)]; ok {
+			return resp, true
+		}
+	}
+	return response{}, false
+}
+
+func (op *Operation) name() string {
+	if op.ID != "" {
+		return op.ID
+	}
+	return op.Method + " " + op.Path
+}
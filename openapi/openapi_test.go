@@ -0,0 +1,132 @@
+package openapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/syavorsky/reqstrategy"
+)
+
+const document = `{
+  "openapi": "3.0.0",
+  "paths": {
+    "/users/{id}": {
+      "get": {
+        "operationId": "getUserProfile",
+        "responses": {
+          "200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}},
+          "404": {"description": "not found"}
+        }
+      },
+      "delete": {
+        "responses": {"2XX": {"description": "deleted"}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "User": {
+        "type": "object",
+        "required": ["id", "name"],
+        "properties": {
+          "id": {"type": "integer"},
+          "name": {"type": "string"},
+          "role": {"type": "string", "enum": ["admin", "user"]},
+          "tags": {"type": "array", "items": {"type": "string"}}
+        }
+      }
+    }
+  }
+}`
+
+type transport func(r *http.Request) (*http.Response, error)
+
+func (t transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return t(r)
+}
+
+func reply(status int, contentType, body string) *http.Client {
+	return &http.Client{Transport: transport(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			Request:    r,
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {contentType}},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	})}
+}
+
+func Test_Operation_Validate(t *testing.T) {
+	spec, err := Load([]byte(document))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	op := spec.Operation("getUserProfile")
+	if op == nil || op.Method != "GET" || op.Path != "/users/{id}" {
+		t.Fatalf("unexpected operation %+v", op)
+	}
+
+	cases := []struct {
+		status      int
+		contentType string
+		body        string
+		err         string
+	}{
+		{200, "application/json; charset=utf-8", `{"id": 1, "name": "joe", "role": "admin", "tags": ["a"]}`, ""},
+		{404, "text/plain", "not found", ""},
+		{500, "text/plain", "oops", "response status 500 is not documented for getUserProfile"},
+		{200, "text/html", "<html>", `unexpected content type "text/html"`},
+		{200, "application/json", `{"id": 1}`, `body: required property "name" is missing`},
+		{200, "application/json", `{"id": 1.5, "name": "joe"}`, "body.id: expected integer, got number"},
+		{200, "application/json", `{"id": 1, "name": "joe", "tags": [1]}`, "body.tags[0]: expected string, got number"},
+		{200, "application/json", `{"id": 1, "name": "joe", "role": "root"}`, "body.role: value root is not one of [admin user]"},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", "http://localhost/users/1", nil)
+		resp, err := reqstrategy.Do(reply(c.status, c.contentType, c.body), op.WithValidator(req))
+		if c.err == "" && err != nil {
+			t.Fatalf("unexpected error for %s: %s", c.body, err)
+		}
+		if c.err != "" && (err == nil || !strings.HasSuffix(err.Error(), c.err)) {
+			t.Fatalf("expected %q error for %s, got %v", c.err, c.body, err)
+		}
+		if body, _ := ioutil.ReadAll(resp.Body); string(body) != c.body {
+			t.Fatalf("expected body to stay readable, got %q", body)
+		}
+	}
+}
+
+func Test_Spec_Register(t *testing.T) {
+	spec, err := Load([]byte(document))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	runner := &reqstrategy.Runner{Client: reply(200, "application/json", `{"id": "1"}`)}
+	spec.Register(runner)
+
+	req, _ := http.NewRequest("GET", "http://localhost/users/1", nil)
+	if _, err := runner.Execute(reqstrategy.WithCallName(req, "getUserProfile")); err == nil {
+		t.Fatal("expected registered operation to validate the response")
+	}
+	if _, err := runner.Execute(req); err != nil {
+		t.Fatalf("expected unnamed call not to be validated, got %s", err)
+	}
+}
+
+func Test_Operation_Rule(t *testing.T) {
+	spec, _ := Load([]byte(document))
+	ops := spec.Operations()
+	if len(ops) != 2 || ops[0].Method != "DELETE" {
+		t.Fatalf("expected operations ordered by method, got %v", ops)
+	}
+	req, _ := http.NewRequest("DELETE", "http://localhost/users/42", nil)
+	if !ops[0].Rule().Match(req) {
+		t.Fatal("expected the rule to match the operation path")
+	}
+	resp := &http.Response{Request: req, StatusCode: 204}
+	if err := ops[0].Validate(resp); err != nil {
+		t.Fatalf("expected 2XX range to cover 204, got %s", err)
+	}
+}
@@ -0,0 +1,121 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// schema is the subset of the OpenAPI schema object used for response validation
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Nullable   bool               `json:"nullable"`
+	Enum       []interface{}      `json:"enum"`
+	Required   []string           `json:"required"`
+	Properties map[string]*schema `json:"properties"`
+	Items      *schema            `json:"items"`
+}
+
+const refPrefix = "#/components/schemas/"
+
+// validate checks the decoded JSON value against the schema, at describes the value location for errors
+func (s *Spec) validate(sch *schema, value interface{}, at string) error {
+	for sch.Ref != "" {
+		if !strings.HasPrefix(sch.Ref, refPrefix) {
+			return fmt.Errorf("unsupported schema reference %q", sch.Ref)
+		}
+		ref, ok := s.schemas[strings.TrimPrefix(sch.Ref, refPrefix)]
+		if !ok {
+			return fmt.Errorf("unknown schema reference %q", sch.Ref)
+		}
+		sch = ref
+	}
+
+	if value == nil {
+		if sch.Nullable || sch.Type == "" {
+			return nil
+		}
+		return fmt.Errorf("%s: expected %s, got null", at, sch.Type)
+	}
+
+	if len(sch.Enum) != 0 {
+		var found bool
+		for _, v := range sch.Enum {
+			if reflect.DeepEqual(v, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value %v is not one of %v", at, value, sch.Enum)
+		}
+	}
+
+	switch sch.Type {
+	case "":
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected object, got %s", at, kind(value))
+		}
+		for _, name := range sch.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s: required property %q is missing", at, name)
+			}
+		}
+		names := make([]string, 0, len(sch.Properties))
+		for name := range sch.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if v, ok := object[name]; ok {
+				if err := s.validate(sch.Properties[name], v, at+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected array, got %s", at, kind(value))
+		}
+		if sch.Items != nil {
+			for i, v := range array {
+				if err := s.validate(sch.Items, v, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: expected integer, got %s", at, kind(value))
+		}
+	default:
+		if kind(value) != sch.Type {
+			return fmt.Errorf("%s: expected %s, got %s", at, sch.Type, kind(value))
+		}
+	}
+	return nil
+}
+
+// kind returns the JSON type name of the decoded value
+func kind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
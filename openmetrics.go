@@ -0,0 +1,111 @@
+package reqstrategy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WriteOpenMetrics writes the metrics in OpenMetrics text format, so they can be exposed on a debug endpoint
+// for scraping without a metrics client library. Traffic is reported by host and by tag, response latency
+// and clock skew by host
+func (s *Stats) WriteOpenMetrics(w io.Writer) error {
+	s.mu.Lock()
+	hosts := copyTraffic(s.hosts)
+	tags := copyTraffic(s.tags)
+	var skewed, observed []string
+	skew := make(map[string]time.Duration, len(s.skew))
+	for host, d := range s.skew {
+		skew[host] = d
+		skewed = append(skewed, host)
+	}
+	latencies := make(map[string]*Histogram, len(s.latencies))
+	for host, h := range s.latencies {
+		latencies[host] = h
+		observed = append(observed, host)
+	}
+	s.mu.Unlock()
+	sort.Strings(skewed)
+	sort.Strings(observed)
+
+	b := bufio.NewWriter(w)
+	writeTraffic(b, "reqstrategy_host", "host", hosts)
+	writeTraffic(b, "reqstrategy_tag", "tag", tags)
+
+	if len(skew) != 0 {
+		b.WriteString("# TYPE reqstrategy_clock_skew_seconds gauge\n")
+		b.WriteString("# UNIT reqstrategy_clock_skew_seconds seconds\n")
+		b.WriteString("# HELP reqstrategy_clock_skew_seconds Estimated difference between the host clock and local time.\n")
+		for _, host := range skewed {
+			fmt.Fprintf(b, "reqstrategy_clock_skew_seconds{host=%s} %s\n", labelValue(host), seconds(skew[host]))
+		}
+	}
+
+	if len(latencies) != 0 {
+		b.WriteString("# TYPE reqstrategy_response_seconds histogram\n")
+		b.WriteString("# UNIT reqstrategy_response_seconds seconds\n")
+		b.WriteString("# HELP reqstrategy_response_seconds Time it took the host to respond with headers.\n")
+		for _, host := range observed {
+			h := latencies[host]
+			h.mu.Lock()
+			label := labelValue(host)
+			var cumulative int64
+			for i, bound := range h.bounds {
+				cumulative += h.counts[i]
+				fmt.Fprintf(b, "reqstrategy_response_seconds_bucket{host=%s,le=\"%s\"} %d\n", label, seconds(bound), cumulative)
+			}
+			fmt.Fprintf(b, "reqstrategy_response_seconds_bucket{host=%s,le=\"+Inf\"} %d\n", label, h.total)
+			fmt.Fprintf(b, "reqstrategy_response_seconds_sum{host=%s} %s\n", label, seconds(h.sum))
+			fmt.Fprintf(b, "reqstrategy_response_seconds_count{host=%s} %d\n", label, h.total)
+			h.mu.Unlock()
+		}
+	}
+
+	b.WriteString("# EOF\n")
+	return b.Flush()
+}
+
+func writeTraffic(w *bufio.Writer, prefix, label string, traffic map[string]Traffic) {
+	var names []string
+	for k := range traffic {
+		// traffic of untagged requests
+		if k != "" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return
+	}
+	for _, direction := range []string{"sent", "received"} {
+		name := prefix + "_" + direction + "_bytes"
+		fmt.Fprintf(w, "# TYPE %s counter\n# UNIT %s bytes\n# HELP %s Body bytes %s.\n", name, name, name, direction)
+		for _, k := range names {
+			n := traffic[k].Sent
+			if direction == "received" {
+				n = traffic[k].Received
+			}
+			fmt.Fprintf(w, "%s_total{%s=%s} %d\n", name, label, labelValue(k), n)
+		}
+	}
+}
+
+func copyTraffic(m map[string]*Traffic) map[string]Traffic {
+	c := make(map[string]Traffic, len(m))
+	for k, t := range m {
+		c[k] = *t
+	}
+	return c
+}
+
+func labelValue(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'g', -1, 64)
+}
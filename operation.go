@@ -0,0 +1,81 @@
+package reqstrategy
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrOperationPending is returned by Operation when the intervals are over before the operation completed
+var ErrOperationPending = errors.New("operation still pending")
+
+// Operation submits the long-running operation and polls its status until done, i.e. the 202 Accepted with
+// Location pattern of async APIs. Status request is built from the submit response with status, GET of the
+// Location header is polled if status is <nil>, it shares the submit request context but not its validators.
+// Polls are sent after intervals, failed polls are tolerated. done tells whether the status response is
+// terminal, its error fails the operation right away. The terminal status response is returned, other bodies
+// are closed. ErrOperationPending or the error of the last poll is returned once intervals are over
+func Operation(client *http.Client, submit *http.Request, status func(*http.Response) (*http.Request, error), done func(*http.Response) (bool, error), intervals ...time.Duration) (*http.Response, error) {
+	submit = withStrategy(submit, "Operation")
+	resp, err := Do(client, submit)
+	if err != nil {
+		return resp, err
+	}
+	if status == nil {
+		status = func(resp *http.Response) (*http.Request, error) {
+			return locationRequest(submit, resp)
+		}
+	}
+	poll, err := status(resp)
+	closeBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	poll = withStrategy(poll, "Operation")
+
+	ctx := poll.Context()
+	clock := clockOf(poll)
+	err = ErrOperationPending
+	for n := 1; len(intervals) != 0; n++ {
+		select {
+		case <-clock.After(intervals[0]):
+			intervals = intervals[1:]
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var resp *http.Response
+		resp, err = Do(client, withChildID(poll, n))
+		if err != nil {
+			closeBody(resp)
+			continue
+		}
+		var finished bool
+		finished, err = done(resp)
+		if err == nil && finished {
+			return resp, nil
+		}
+		closeBody(resp)
+		if err != nil {
+			return nil, err
+		}
+		err = ErrOperationPending
+	}
+	return nil, err
+}
+
+// locationRequest builds GET of the response Location header, the request validators are not inherited
+func locationRequest(r *http.Request, resp *http.Response) (*http.Request, error) {
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return nil, errors.New("no Location header in the submit response")
+	}
+	u, err := r.URL.Parse(location)
+	if err != nil {
+		return nil, err
+	}
+	poll, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return withValue(poll.WithContext(r.Context()), keyValidators, []validator(nil)), nil
+}
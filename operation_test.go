@@ -0,0 +1,63 @@
+package reqstrategy
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy/reqstrategytest"
+)
+
+func Test_Operation(t *testing.T) {
+	var polls int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.Method == "POST" {
+			header := http.Header{"Location": {"/operations/1"}}
+			return &http.Response{Request: r, StatusCode: 202, Header: header, Body: http.NoBody}, nil
+		}
+		polls++
+		switch polls {
+		case 1:
+			return nil, fmt.Errorf("connection reset")
+		case 2:
+			return &http.Response{Request: r, StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("running"))}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("done"))}, nil
+	})
+	done := func(resp *http.Response) (bool, error) {
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+		return string(body) == "done", err
+	}
+
+	submit, _ := http.NewRequest("POST", "http://localhost/operations", nil)
+	submit = WithClock(WithStatusRequired(submit, 202), reqstrategytest.NewVirtualClock(time.Now()))
+	resp, err := Operation(client, submit, nil, done, time.Second, time.Second, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.Request.URL.String() != "http://localhost/operations/1" {
+		t.Fatalf("expected status polled from Location, got %s", resp.Request.URL)
+	}
+	if polls != 3 {
+		t.Fatalf("expected 3 polls, got %d", polls)
+	}
+
+	polls = 1
+	_, err = Operation(client, submit, nil, done, time.Second)
+	if err != ErrOperationPending {
+		t.Fatalf("expected ErrOperationPending, got %v", err)
+	}
+
+	failed := errors.New("operation failed")
+	_, err = Operation(client, submit, nil, func(*http.Response) (bool, error) {
+		return true, failed
+	}, time.Second)
+	if err != failed {
+		t.Fatalf("expected operation failure, got %v", err)
+	}
+}
@@ -1,9 +1,13 @@
 package reqstrategy
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // WithValidator introduces a response validator function to the context to be used in Do/Race/All/Some/Retry
@@ -28,3 +32,208 @@ func WithStatusRequired(r *http.Request, codes ...int) *http.Request {
 		return fmt.Errorf("%s %s: expected response status %v, got %d", r.Request.Method, r.Request.URL, codes, r.StatusCode)
 	})
 }
+
+// InterceptionError is returned by the WithInterceptionCheck validator when the response looks like
+// it was produced by a captive portal or an intercepting proxy rather than by the requested server
+type InterceptionError struct {
+	Response *http.Response
+	Reason   string
+}
+
+func (e *InterceptionError) Error() string {
+	r := e.Response.Request
+	return fmt.Sprintf("%s %s: request intercepted: %s", r.Method, r.URL, e.Reason)
+}
+
+// WithInterceptionCheck adds the response validator detecting common interception signatures:
+// redirects to another host, HTML served to a request not accepting HTML, and TLS certificates
+// issued by someone not listed in issuers (issuer check is skipped if no issuers provided).
+// Failures are reported as *InterceptionError so network hijacking can be told apart from server errors
+func WithInterceptionCheck(r *http.Request, issuers ...string) *http.Request {
+	return WithValidator(r, func(r *http.Response) error {
+		original := r.Request
+		for original.Response != nil && original.Response.Request != nil {
+			original = original.Response.Request
+		}
+		if original.URL.Host != r.Request.URL.Host {
+			return &InterceptionError{r, fmt.Sprintf("redirected to %s", r.Request.URL)}
+		}
+
+		contentType := r.Header.Get("Content-Type")
+		accept := original.Header.Get("Accept")
+		if strings.HasPrefix(contentType, "text/html") && accept != "" && !strings.Contains(accept, "html") {
+			return &InterceptionError{r, fmt.Sprintf("unexpected content type %q", contentType)}
+		}
+
+		if len(issuers) != 0 && r.TLS != nil && len(r.TLS.PeerCertificates) != 0 {
+			issuer := r.TLS.PeerCertificates[0].Issuer
+			for _, name := range issuers {
+				if issuer.CommonName == name {
+					return nil
+				}
+				for _, org := range issuer.Organization {
+					if org == name {
+						return nil
+					}
+				}
+			}
+			return &InterceptionError{r, fmt.Sprintf("unexpected certificate issuer %q", issuer.String())}
+		}
+		return nil
+	})
+}
+
+// WithTag marks the request with a feature tag, used to attribute request metrics, see Stats
+func WithTag(r *http.Request, tag string) *http.Request {
+	return withValue(r, keyTag, tag)
+}
+
+// ErrExpired is returned when the request is not sent because it outlived its expiry, see WithExpiry
+var ErrExpired = errors.New("request expired")
+
+// WithExpiry sets the useful lifetime of the request counting from now. Expired request is not sent
+// anymore and strategies like Retry give up with ErrExpired instead of waiting for the next attempt
+// which would happen past the expiry
+func WithExpiry(r *http.Request, d time.Duration) *http.Request {
+	return withValue(r, keyExpiry, clockOf(r).Now().Add(d))
+}
+
+// expired reports whether the request will be expired after d from now
+func expired(r *http.Request, d time.Duration) bool {
+	expiry, ok := r.Context().Value(keyExpiry).(time.Time)
+	return ok && !clockOf(r).Now().Add(d).Before(expiry)
+}
+
+// WithClockCorrection makes Do recover from "request time too skewed" kind of errors. When match reports
+// the response as caused by the clock difference, sign is called with the request and current time
+// adjusted by the skew (taken from Stats when set with WithStats or from the response Date header otherwise)
+// and the returned request is sent once more. Request body is sent again with GetBody, the body without GetBody
+// is buffered up to MaxBufferedBody, ErrBodyNotRewindable is returned if the larger one has to be sent again
+func WithClockCorrection(r *http.Request, match func(*http.Response) bool, sign func(r *http.Request, now time.Time) (*http.Request, error)) *http.Request {
+	return withValue(r, keyClockCorrection, &clockCorrection{match, sign})
+}
+
+// MatchBody returns response matcher for WithClockCorrection reporting whether the response body contains
+// any of provided substrings, e.g. MatchBody("RequestTimeTooSkewed") for S3-compatible APIs.
+// Body is kept available for reading after the check
+func MatchBody(substrings ...string) func(*http.Response) bool {
+	return func(r *http.Response) bool {
+		body, _ := bufferBody(r)
+		for _, s := range substrings {
+			if bytes.Contains(body, []byte(s)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// TruncatedError is returned by the WithCompleteBody validator when the response body ended prematurely
+type TruncatedError struct {
+	Response *http.Response
+	Err      error
+}
+
+func (e *TruncatedError) Error() string {
+	r := e.Response.Request
+	return fmt.Sprintf("%s %s: truncated response body: %s", r.Method, r.URL, e.Err)
+}
+
+// Temporary reports the error as worth retrying
+func (e *TruncatedError) Temporary() bool {
+	return true
+}
+
+// WithCompleteBody adds the response validator reading the whole body to make sure it was not truncated,
+// e.g. the connection was dropped before the terminating chunk or Content-Length bytes were received.
+// Failures are reported as *TruncatedError. Body stays available for reading from memory after the check
+func WithCompleteBody(r *http.Request) *http.Request {
+	return WithValidator(r, func(r *http.Response) error {
+		body, err := bufferBody(r)
+		bodyless := r.Request.Method == "HEAD" || r.StatusCode == http.StatusNoContent || r.StatusCode == http.StatusNotModified
+		if err == nil && !bodyless && r.ContentLength >= 0 && int64(len(body)) != r.ContentLength {
+			err = fmt.Errorf("expected %d bytes, got %d", r.ContentLength, len(body))
+		}
+		if err != nil {
+			return &TruncatedError{r, err}
+		}
+		return nil
+	})
+}
+
+// WithTrailerValidator adds the response validator run once the body is fully read, so it can inspect
+// HTTP trailers in Response.Trailer. Body stays available for reading from memory after the check
+func WithTrailerValidator(r *http.Request, validate func(r *http.Response) error) *http.Request {
+	return WithValidator(r, func(r *http.Response) error {
+		if _, err := bufferBody(r); err != nil {
+			return fmt.Errorf("%s %s: failed to read response body: %s", r.Request.Method, r.Request.URL, err)
+		}
+		return validate(r)
+	})
+}
+
+// WithTrailerRequired adds the trailer validator by listing acceptable values of the trailer,
+// e.g. WithTrailerRequired(r, "Grpc-Status", "0")
+func WithTrailerRequired(r *http.Request, name string, values ...string) *http.Request {
+	return WithTrailerValidator(r, func(r *http.Response) error {
+		value := r.Trailer.Get(name)
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s %s: expected %s trailer %v, got %q", r.Request.Method, r.Request.URL, name, values, value)
+	})
+}
+
+// WithNoRetry makes the request sent once even by strategies which retry, e.g. Retry, Policy or Queue with AtLeastOnce,
+// so dangerous non-idempotent calls can opt out of the generic infrastructure retrying by default
+func WithNoRetry(r *http.Request) *http.Request {
+	return withValue(r, keyNoRetry, true)
+}
+
+// WithNoHedge makes strategies sending extra copies of the request, i.e. Hedge, Speculate and Policy
+// with Endpoints, send it once to the first endpoint
+func WithNoHedge(r *http.Request) *http.Request {
+	return withValue(r, keyNoHedge, true)
+}
+
+// WithBackoff makes Retry wait between attempts as the backoff tells instead of the intervals it is given,
+// e.g. for unbounded exponential backoff limited with WithMaxElapsed
+func WithBackoff(r *http.Request, backoff Backoff) *http.Request {
+	return withValue(r, keyBackoff, backoff)
+}
+
+// WithMaxAttempts limits the number of attempts made by strategies retrying the request, e.g. Retry, RetryBackoff
+// or AllWithRetry, whatever their intervals or backoff allow. Retries made by Do within every attempt are limited
+// by RetryPolicy.MaxAttempts instead, see WithRetryPolicy
+func WithMaxAttempts(r *http.Request, n int) *http.Request {
+	return withValue(r, keyMaxAttempts, n)
+}
+
+// WithMaxElapsed limits the time strategies retrying the request spend on it, the attempt is not made if it would
+// start later than d after the first one. Unlike WithExpiry and the context deadline, the attempt in flight is
+// not cancelled and its outcome is returned. Like WithMaxAttempts, it does not limit WithRetryPolicy retries
+func WithMaxElapsed(r *http.Request, d time.Duration) *http.Request {
+	return withValue(r, keyMaxElapsed, d)
+}
+
+// plannedAttempts returns the number of attempts retrying with intervals or backoff is planned for, see planRequests
+func plannedAttempts(r *http.Request, attempts int) int {
+	if n, _ := r.Context().Value(keyMaxAttempts).(int); n > 0 && n < attempts {
+		return n
+	}
+	return attempts
+}
+
+// noRetry reports whether the request must not be retried, see WithNoRetry
+func noRetry(r *http.Request) bool {
+	marked, _ := r.Context().Value(keyNoRetry).(bool)
+	return marked
+}
+
+// noHedge reports whether the request must not be hedged, see WithNoHedge
+func noHedge(r *http.Request) bool {
+	marked, _ := r.Context().Value(keyNoHedge).(bool)
+	return marked
+}
@@ -0,0 +1,261 @@
+package reqstrategy
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_WithInterceptionCheck(t *testing.T) {
+	req := newRequest(t, "api")
+	req.Header.Set("Accept", "application/json")
+	req = WithInterceptionCheck(req)
+
+	validators, _ := req.Context().Value(keyValidators).([]validator)
+	if len(validators) != 1 {
+		t.Fatalf("expected 1 validator to be set, got %d", len(validators))
+	}
+
+	portal, _ := url.Parse("http://portal.local/login")
+	redirected := &http.Request{Method: "GET", URL: portal, Response: &http.Response{Request: req}}
+	err := validators[0](&http.Response{Request: redirected, StatusCode: 200})
+	if _, ok := err.(*InterceptionError); !ok {
+		t.Fatalf("expected *InterceptionError, got %v", err)
+	}
+	want := "GET http://portal.local/login: request intercepted: redirected to http://portal.local/login"
+	if err.Error() != want {
+		t.Fatalf(`expected "%s" error, got "%s"`, want, err.Error())
+	}
+
+	html := &http.Response{Request: req, StatusCode: 200, Header: http.Header{"Content-Type": {"text/html; charset=utf-8"}}}
+	if _, ok := validators[0](html).(*InterceptionError); !ok {
+		t.Fatal("expected HTML response to be reported as intercepted")
+	}
+
+	json := &http.Response{Request: req, StatusCode: 200, Header: http.Header{"Content-Type": {"application/json"}}}
+	if err := validators[0](json); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func Test_WithClockCorrection(t *testing.T) {
+	server := time.Now().Add(time.Hour)
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		signed, _ := time.Parse(time.RFC3339, r.Header.Get("X-Date"))
+		if signed.Sub(server) < -time.Minute {
+			return &http.Response{
+				Request:    r,
+				StatusCode: 403,
+				Header:     http.Header{"Date": {server.UTC().Format(http.TimeFormat)}},
+				Body:       ioutil.NopCloser(strings.NewReader("<Code>RequestTimeTooSkewed</Code>")),
+			}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	var signed int
+	sign := func(r *http.Request, now time.Time) (*http.Request, error) {
+		signed++
+		r.Header.Set("X-Date", now.Format(time.RFC3339))
+		return r, nil
+	}
+
+	req := newRequest(t)
+	req, _ = sign(req, time.Now())
+	req = WithStatusRequired(req, 200)
+	req = WithClockCorrection(req, MatchBody("RequestTimeTooSkewed"), sign)
+
+	resp, err := Do(client, req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected response status 200, got %d", resp.StatusCode)
+	}
+	if signed != 2 {
+		t.Fatalf("expected request to be signed twice, got %d", signed)
+	}
+}
+
+func Test_WithClockCorrection_body(t *testing.T) {
+	server := time.Now().Add(time.Hour)
+	var bodies []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		signed, _ := time.Parse(time.RFC3339, r.Header.Get("X-Date"))
+		if signed.Sub(server) < -time.Minute {
+			return &http.Response{
+				Request:    r,
+				StatusCode: 403,
+				Header:     http.Header{"Date": {server.UTC().Format(http.TimeFormat)}},
+				Body:       ioutil.NopCloser(strings.NewReader("<Code>RequestTimeTooSkewed</Code>")),
+			}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	sign := func(r *http.Request, now time.Time) (*http.Request, error) {
+		r.Header.Set("X-Date", now.Format(time.RFC3339))
+		return r, nil
+	}
+
+	req, _ := http.NewRequest("PUT", "http://localhost/object", ioutil.NopCloser(strings.NewReader("payload")))
+	req, _ = sign(req, time.Now())
+	req = WithClockCorrection(WithStatusRequired(req, 200), MatchBody("RequestTimeTooSkewed"), sign)
+
+	if _, err := Do(client, req); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(bodies) != 2 || bodies[0] != "payload" || bodies[1] != "payload" {
+		t.Fatalf("expected the body sent again after the correction, got %q", bodies)
+	}
+}
+
+func Test_WithExpiry(t *testing.T) {
+	var count int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		count++
+		return &http.Response{Request: r, StatusCode: 500}, nil
+	})
+
+	req := WithExpiry(WithStatusRequired(newRequest(t), 200), 250*time.Millisecond)
+	_, err := Retry(client, req, 100*time.Millisecond, 100*time.Millisecond, 100*time.Millisecond)
+	if err != ErrExpired {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+	if count != 3 {
+		t.Fatalf("expected 3 attempts before expiry, got %d", count)
+	}
+
+	if _, err := Do(client, WithExpiry(newRequest(t), 0)); err != ErrExpired {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+}
+
+func Test_WithCompleteBody(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		body := ioutil.NopCloser(io.MultiReader(strings.NewReader("partial"), &errReader{io.ErrUnexpectedEOF}))
+		if r.URL.Path == "/short" {
+			body = ioutil.NopCloser(strings.NewReader("partial"))
+		}
+		if r.URL.Path == "/complete" {
+			body = ioutil.NopCloser(strings.NewReader("complete"))
+		}
+		return &http.Response{Request: r, StatusCode: 200, ContentLength: 8, Body: body}, nil
+	})
+
+	for path, want := range map[string]string{
+		"chunked":  "GET http://localhost/chunked: truncated response body: unexpected EOF",
+		"short":    "GET http://localhost/short: truncated response body: expected 8 bytes, got 7",
+		"complete": "",
+	} {
+		resp, err := Do(client, WithCompleteBody(newRequest(t, path)))
+		if want == "" {
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if body, _ := ioutil.ReadAll(resp.Body); string(body) != "complete" {
+				t.Fatalf(`expected body to stay readable, got "%s"`, body)
+			}
+			continue
+		}
+		if _, ok := err.(*TruncatedError); !ok || err.Error() != want {
+			t.Fatalf(`expected "%s" *TruncatedError, got %v`, want, err)
+		}
+	}
+}
+
+func Test_WithTrailerRequired(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		resp := &http.Response{Request: r, StatusCode: 200, Trailer: http.Header{}}
+		status := r.URL.Query().Get("status")
+		// trailers become available only once the body is read
+		resp.Body = ioutil.NopCloser(io.MultiReader(strings.NewReader("data"), readerFunc(func([]byte) (int, error) {
+			resp.Trailer.Set("Grpc-Status", status)
+			return 0, io.EOF
+		})))
+		return resp, nil
+	})
+
+	ok, _ := http.NewRequest("GET", "http://localhost/?status=0", nil)
+	if _, err := Do(client, WithTrailerRequired(ok, "Grpc-Status", "0")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	failed, _ := http.NewRequest("GET", "http://localhost/?status=13", nil)
+	_, err := Do(client, WithTrailerRequired(failed, "Grpc-Status", "0"))
+	want := `GET http://localhost/?status=13: expected Grpc-Status trailer [0], got "13"`
+	if err == nil || err.Error() != want {
+		t.Fatalf(`expected "%s" error, got %v`, want, err)
+	}
+}
+
+type readerFunc func([]byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+func Test_WithNoRetry(t *testing.T) {
+	var calls int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{Request: r, StatusCode: 503}, nil
+	})
+	req := WithNoRetry(WithStatusRequired(newRequest(t), 200))
+
+	strategies := map[string]struct {
+		requests int32
+		execute  func() error
+	}{
+		"Retry": {1, func() error {
+			_, err := Retry(client, req, time.Millisecond, time.Millisecond)
+			return err
+		}},
+		"Policy": {1, func() error {
+			_, err := Policy{Intervals: []time.Duration{time.Millisecond}}.Execute(client, req)
+			return err
+		}},
+		"RetryStrategy": {1, func() error {
+			_, err := Compose(RetryStrategy(time.Millisecond), AllStrategy()).Execute(context.Background(), client, req)
+			return err
+		}},
+	}
+	for name, s := range strategies {
+		atomic.StoreInt32(&calls, 0)
+		if err := s.execute(); err == nil {
+			t.Fatalf("%s: expected failure", name)
+		}
+		if n := atomic.LoadInt32(&calls); n != s.requests {
+			t.Fatalf("%s: expected %d requests, got %d", name, s.requests, n)
+		}
+	}
+}
+
+func Test_WithNoHedge(t *testing.T) {
+	var calls int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Host != "localhost" {
+			return &http.Response{Request: r, StatusCode: 200}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 503}, nil
+	})
+	req := WithNoHedge(WithStatusRequired(newRequest(t), 200))
+
+	if _, err := Hedge(client, req, time.Millisecond, 2); err == nil || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("expected Hedge to send the request once, got %d requests", calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	resp, err := Policy{Endpoints: []string{"http://primary", "http://secondary"}}.Execute(client, req)
+	if err != nil || resp.Request.URL.Host != "primary" || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("expected Policy to send the request to the first endpoint only, got %d requests", calls)
+	}
+}
@@ -0,0 +1,193 @@
+package reqstrategy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Policy is a declarative description of how requests are executed, meant to be kept in configuration
+// and used with Runner, e.g. runner.Handle(rule, policy.Execute)
+type Policy struct {
+	// Methods lists HTTP methods of the requests the policy is meant for, only used by Validate
+	Methods []string
+	// Statuses lists acceptable response statuses, see WithStatusRequired
+	Statuses []int
+	// Validators lists additional response validators, see WithValidator
+	Validators []func(*http.Response) error
+	// Intervals between retries, see Retry
+	Intervals []time.Duration
+	// MaxElapsed caps the total time of the call including all retries
+	MaxElapsed time.Duration
+	// Endpoints lists base URLs the request is raced across, only scheme and host are taken from them
+	Endpoints []string
+	// Hedges makes the request sent to the first endpoint and up to Hedges next ones one by one, HedgeDelay
+	// apart, instead of all Endpoints at once, see WithStagger. Zero races all endpoints at once
+	Hedges     int
+	HedgeDelay time.Duration
+	// IdempotencyKey makes requests sent with IdempotencyKeyHeader, unless they already have one
+	IdempotencyKey bool
+}
+
+// Warning describes a questionable Policy setting found by Validate
+type Warning struct {
+	Field   string
+	Message string
+}
+
+func (w Warning) String() string {
+	return w.Field + ": " + w.Message
+}
+
+// Validate detects nonsensical combinations of settings, returning no warnings for a sound policy
+func (p Policy) Validate() []Warning {
+	var warnings []Warning
+	warn := func(field, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{field, fmt.Sprintf(format, args...)})
+	}
+
+	var total time.Duration
+	for i, interval := range p.Intervals {
+		if interval < 0 {
+			warn("Intervals", "interval #%d is negative", i)
+		}
+		total += interval
+	}
+	if p.MaxElapsed < 0 {
+		warn("MaxElapsed", "is negative")
+	}
+	if p.MaxElapsed > 0 && len(p.Intervals) == 0 {
+		warn("MaxElapsed", "has no effect without retry intervals")
+	}
+	if p.MaxElapsed > 0 && total > p.MaxElapsed {
+		warn("Intervals", "add up to %s exceeding MaxElapsed %s, last retries will never happen", total, p.MaxElapsed)
+	}
+	if len(p.Intervals) != 0 && !p.IdempotencyKey {
+		for _, method := range p.Methods {
+			if method == "POST" || method == "PATCH" {
+				warn("IdempotencyKey", "is not set while %s requests are retried and may be applied twice", method)
+			}
+		}
+	}
+	for _, status := range p.Statuses {
+		if status < 100 || status > 599 {
+			warn("Statuses", "%d is not a valid HTTP status", status)
+		}
+	}
+	if len(p.Endpoints) == 1 {
+		warn("Endpoints", "single endpoint has nothing to race with")
+	}
+	if p.Hedges < 0 {
+		warn("Hedges", "is negative")
+	}
+	if p.Hedges > 0 && p.Hedges >= len(p.Endpoints) {
+		warn("Hedges", "%d hedges need %d endpoints, only %d are set", p.Hedges, p.Hedges+1, len(p.Endpoints))
+	}
+	if p.HedgeDelay != 0 && p.Hedges <= 0 {
+		warn("HedgeDelay", "has no effect without Hedges")
+	}
+	return warnings
+}
+
+// merge returns the policy with the fields set in the override replaced
+func (p Policy) merge(override Policy) Policy {
+	if override.Methods != nil {
+		p.Methods = override.Methods
+	}
+	if override.Statuses != nil {
+		p.Statuses = override.Statuses
+	}
+	if override.Validators != nil {
+		p.Validators = override.Validators
+	}
+	if override.Intervals != nil {
+		p.Intervals = override.Intervals
+	}
+	if override.MaxElapsed != 0 {
+		p.MaxElapsed = override.MaxElapsed
+	}
+	if override.Endpoints != nil {
+		p.Endpoints = override.Endpoints
+	}
+	if override.Hedges != 0 {
+		p.Hedges = override.Hedges
+	}
+	if override.HedgeDelay != 0 {
+		p.HedgeDelay = override.HedgeDelay
+	}
+	if override.IdempotencyKey {
+		p.IdempotencyKey = true
+	}
+	return p
+}
+
+// width returns the number of endpoints every attempt is sent to in the worst case
+func (p Policy) width() int {
+	width := len(p.Endpoints)
+	if p.Hedges > 0 && p.Hedges+1 < width {
+		width = p.Hedges + 1
+	}
+	if width == 0 {
+		width = 1
+	}
+	return width
+}
+
+// Execute runs the request according to the policy. It fails with ErrAmplificationExceeded before sending
+// anything if all attempts to all endpoints would exceed the limit set with WithMaxRequests
+func (p Policy) Execute(client *http.Client, r *http.Request) (*http.Response, error) {
+	r = withStrategy(r, "Policy")
+	if len(p.Statuses) != 0 {
+		r = WithStatusRequired(r, p.Statuses...)
+	}
+	for _, validate := range p.Validators {
+		r = WithValidator(r, validate)
+	}
+	if p.IdempotencyKey && r.Header.Get(IdempotencyKeyHeader) == "" {
+		r = withHeader(r, IdempotencyKeyHeader, NewCallID())
+	}
+	cancel := context.CancelFunc(func() {})
+	if p.MaxElapsed > 0 {
+		var ctx context.Context
+		ctx, cancel = context.WithTimeout(r.Context(), p.MaxElapsed)
+		r = r.WithContext(ctx)
+	}
+
+	r, err := rewindable(r, MaxBufferedBody)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	width := p.width()
+	if noHedge(r) {
+		width = 1
+	}
+	if err := planRequests([]*http.Request{r}, plannedAttempts(r, len(p.Intervals)+1)*width); err != nil {
+		cancel()
+		return nil, err
+	}
+	resp, err := retry(r, p.Intervals, func(attempt int) (*http.Response, error) {
+		req, err := rewind(r, attempt)
+		if err != nil {
+			return nil, err
+		}
+		req = withAttempt(req, attempt)
+		if len(p.Endpoints) == 0 {
+			return Do(client, req)
+		}
+		endpoints := p.Endpoints[:width]
+		if p.Hedges > 0 {
+			req = WithStagger(req, p.HedgeDelay, 0)
+		}
+		legs := make([]*http.Request, len(endpoints))
+		for i, endpoint := range endpoints {
+			if legs[i], err = retarget(req, endpoint); err != nil {
+				return nil, err
+			}
+		}
+		return Race(client, legs...)
+	})
+	cancelOnClose(resp, cancel)
+	return resp, err
+}
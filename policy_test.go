@@ -0,0 +1,90 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Policy_Validate(t *testing.T) {
+	policy := Policy{
+		Methods:    []string{"GET", "POST"},
+		Intervals:  []time.Duration{time.Second, 2 * time.Second},
+		MaxElapsed: 2 * time.Second,
+		Statuses:   []int{200, 1000},
+	}
+
+	var got []string
+	for _, w := range policy.Validate() {
+		got = append(got, w.String())
+	}
+	want := []string{
+		"Intervals: add up to 3s exceeding MaxElapsed 2s, last retries will never happen",
+		"IdempotencyKey: is not set while POST requests are retried and may be applied twice",
+		"Statuses: 1000 is not a valid HTTP status",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("expected warnings\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	if warnings := (Policy{Intervals: []time.Duration{time.Second}}).Validate(); len(warnings) != 0 {
+		t.Fatalf("expected no warnings, got %v", warnings)
+	}
+
+	warnings := (Policy{Endpoints: []string{"http://a", "http://b"}, Hedges: 2}).Validate()
+	if len(warnings) != 1 || warnings[0].String() != "Hedges: 2 hedges need 3 endpoints, only 2 are set" {
+		t.Fatalf("expected hedging wider than the endpoints reported, got %v", warnings)
+	}
+}
+
+func Test_Policy_Execute(t *testing.T) {
+	var hosts []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		hosts = append(hosts, r.URL.Host)
+		if len(hosts) < 2 || r.Header.Get(IdempotencyKeyHeader) == "" {
+			return &http.Response{Request: r, StatusCode: 503}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	policy := Policy{
+		Statuses:       []int{200},
+		Intervals:      []time.Duration{time.Millisecond},
+		IdempotencyKey: true,
+		Endpoints:      []string{"http://replica"},
+	}
+	resp, err := policy.Execute(client, newRequest(t, "users"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.Request.URL.String() != "http://replica/users" {
+		t.Fatalf(`expected response from "http://replica/users", got "%s"`, resp.Request.URL)
+	}
+	if len(hosts) != 2 {
+		t.Fatalf("expected 2 attempts, got %v", hosts)
+	}
+}
+
+func Test_Policy_Execute_maxRequests(t *testing.T) {
+	var sent int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&sent, 1)
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	policy := Policy{
+		Intervals: []time.Duration{time.Millisecond, time.Millisecond},
+		Endpoints: []string{"http://a", "http://b"},
+	}
+	if _, err := policy.Execute(client, WithMaxRequests(newRequest(t), 5)); err != ErrAmplificationExceeded {
+		t.Fatalf("expected ErrAmplificationExceeded, got %v", err)
+	}
+	if sent != 0 {
+		t.Fatalf("expected no requests to be sent, got %d", sent)
+	}
+	if _, err := policy.Execute(client, WithMaxRequests(newRequest(t), 6)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
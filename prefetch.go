@@ -0,0 +1,38 @@
+package reqstrategy
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// PrefetchConcurrency is the number of requests Prefetch sends at once
+const PrefetchConcurrency = 2
+
+// Prefetch sends requests in background purely for side effects like warming up caching transports
+// or server caches, returning right away. At most PrefetchConcurrency requests are in flight, response
+// bodies are read to the end and closed. Failures are not reported back, use WithHook to log them.
+// Returned channel is closed once all requests are complete
+func Prefetch(client *http.Client, requests ...*http.Request) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		slots := make(chan struct{}, PrefetchConcurrency)
+		for _, r := range requests {
+			r := r
+			slots <- struct{}{}
+			go func() {
+				defer func() { <-slots }()
+				resp, _ := Do(client, r)
+				if resp != nil && resp.Body != nil {
+					io.Copy(ioutil.Discard, resp.Body)
+					resp.Body.Close()
+				}
+			}()
+		}
+		for i := 0; i < PrefetchConcurrency; i++ {
+			slots <- struct{}{}
+		}
+	}()
+	return done
+}
@@ -0,0 +1,49 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Prefetch(t *testing.T) {
+	var inflight, peak, calls int32
+	release := make(chan struct{})
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{StatusCode: 500, Request: r}, nil
+	})
+
+	var requests []*http.Request
+	for i := 0; i < 5; i++ {
+		requests = append(requests, WithStatusRequired(newRequest(t), 200))
+	}
+	start := time.Now()
+	done := Prefetch(client, requests...)
+	if time.Since(start) > 10*time.Millisecond {
+		t.Fatal("expected Prefetch to return right away")
+	}
+	close(release)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected prefetch to complete")
+	}
+	if calls != 5 {
+		t.Fatalf("expected 5 requests, got %d", calls)
+	}
+	if peak > PrefetchConcurrency {
+		t.Fatalf("expected at most %d requests in flight, got %d", PrefetchConcurrency, peak)
+	}
+}
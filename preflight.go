@@ -0,0 +1,42 @@
+package reqstrategy
+
+import (
+	"net/http"
+)
+
+// WithPreflight makes Do send HEAD request with the same URL and headers before the request itself,
+// and send the request only if validate accepted the HEAD response. This way large bodies are not
+// streamed when the server would reject them anyway, and since All cancels the rest of requests
+// on the first failure, the whole fan-out fails early
+func WithPreflight(r *http.Request, validate func(r *http.Response) error) *http.Request {
+	return withValue(r, keyPreflight, validate)
+}
+
+// WithExpectContinue sets "Expect: 100-continue" header so the body is sent only after the server
+// confirmed it would accept the request. It is a lighter alternative to WithPreflight, but requires
+// http.Transport.ExpectContinueTimeout to be set and the server to support it
+func WithExpectContinue(r *http.Request) *http.Request {
+	return withHeader(r, "Expect", "100-continue")
+}
+
+// preflight sends and validates HEAD request if the request has a preflight validator
+func preflight(client *http.Client, r *http.Request) error {
+	validate, ok := r.Context().Value(keyPreflight).(func(r *http.Response) error)
+	if !ok {
+		return nil
+	}
+	head := r.WithContext(r.Context())
+	head.Header = cloneHeader(r.Header)
+	head.Header.Del("Expect")
+	head.Method = "HEAD"
+	head.Body = nil
+	head.GetBody = nil
+	head.ContentLength = 0
+
+	resp, err := client.Do(head)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return validate(resp)
+}
@@ -0,0 +1,48 @@
+package reqstrategy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_WithPreflight(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		sent = append(sent, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if r.Header.Get("Authorization") == "" {
+			return &http.Response{Request: r, StatusCode: 401, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	})
+
+	upload := func(path string, authorized bool) *http.Request {
+		req, _ := http.NewRequest("POST", "http://localhost/"+path, strings.NewReader("large body"))
+		if authorized {
+			req.Header.Set("Authorization", "Bearer token")
+		}
+		req = WithStatusRequired(req, 200)
+		return WithPreflight(req, func(r *http.Response) error {
+			if r.StatusCode != 200 {
+				return fmt.Errorf("not authorized")
+			}
+			return nil
+		})
+	}
+
+	if _, err := Do(client, upload("a", true)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := Do(client, upload("b", false)); err == nil {
+		t.Fatal("expected preflight to fail")
+	}
+	if got := strings.Join(sent, ", "); got != "HEAD /a, POST /a, HEAD /b" {
+		t.Fatalf("unexpected requests %s", got)
+	}
+}
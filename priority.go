@@ -0,0 +1,122 @@
+package reqstrategy
+
+import (
+	"container/heap"
+	"net/http"
+	"sync"
+)
+
+// Limiter caps the number of attempts in flight across all requests it is attached to with WithLimiter,
+// no matter which strategies send them. Once the limit is reached attempts wait for a free slot, higher
+// priority first (see WithPriority), in the order they came within the same priority. Slot is taken
+// until the response headers are received. It is safe for concurrent use
+type Limiter struct {
+	limit int
+
+	mu      sync.Mutex
+	active  int
+	waiting waiters
+	seq     uint64
+}
+
+// NewLimiter creates Limiter allowing up to limit attempts in flight
+func NewLimiter(limit int) *Limiter {
+	return &Limiter{limit: limit}
+}
+
+// WithLimiter makes every attempt of the request take a slot of the limiter before it is sent
+func WithLimiter(r *http.Request, l *Limiter) *http.Request {
+	return withValue(r, keyLimiter, l)
+}
+
+// WithPriority sets the priority the request waits for the Limiter slot with, default is 0,
+// e.g. latency critical requests may go first with 1 while background ones yield with -1
+func WithPriority(r *http.Request, n int) *http.Request {
+	return withValue(r, keyPriority, n)
+}
+
+// acquire waits for the slot of the request limiter, if any, returning the function releasing it
+func acquire(r *http.Request) (func(), error) {
+	l, ok := r.Context().Value(keyLimiter).(*Limiter)
+	if !ok {
+		return func() {}, nil
+	}
+	priority, _ := r.Context().Value(keyPriority).(int)
+
+	l.mu.Lock()
+	if l.active < l.limit && len(l.waiting) == 0 {
+		l.active++
+		l.mu.Unlock()
+		return l.release, nil
+	}
+	l.seq++
+	w := &waiter{priority: priority, seq: l.seq, ready: make(chan struct{})}
+	heap.Push(&l.waiting, w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return l.release, nil
+	case <-r.Context().Done():
+		l.mu.Lock()
+		granted := w.index < 0
+		if !granted {
+			heap.Remove(&l.waiting, w.index)
+		}
+		l.mu.Unlock()
+		if granted {
+			l.release()
+		}
+		return nil, r.Context().Err()
+	}
+}
+
+// release passes the slot to the next waiting attempt or frees it
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.waiting) == 0 {
+		l.active--
+		return
+	}
+	w := heap.Pop(&l.waiting).(*waiter)
+	close(w.ready)
+}
+
+type waiter struct {
+	priority int
+	seq      uint64
+	index    int
+	ready    chan struct{}
+}
+
+// waiters is the heap of attempts waiting for the slot, highest priority first
+type waiters []*waiter
+
+func (w waiters) Len() int {
+	return len(w)
+}
+
+func (w waiters) Less(i, j int) bool {
+	return w[i].priority > w[j].priority || w[i].priority == w[j].priority && w[i].seq < w[j].seq
+}
+
+func (w waiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+
+func (w *waiters) Push(x interface{}) {
+	item := x.(*waiter)
+	item.index = len(*w)
+	*w = append(*w, item)
+}
+
+func (w *waiters) Pop() interface{} {
+	old := *w
+	item := old[len(old)-1]
+	item.index = -1
+	*w = old[:len(old)-1]
+	return item
+}
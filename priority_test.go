@@ -0,0 +1,78 @@
+package reqstrategy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_WithPriority(t *testing.T) {
+	gate := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/first" {
+			<-gate
+		}
+		mu.Lock()
+		order = append(order, r.URL.Path)
+		mu.Unlock()
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	limiter := NewLimiter(1)
+	waiting := func(n int) {
+		for {
+			limiter.mu.Lock()
+			w := len(limiter.waiting)
+			limiter.mu.Unlock()
+			if w == n {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	var wg sync.WaitGroup
+	send := func(path string, priority int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := Do(client, WithPriority(WithLimiter(newRequest(t, path), limiter), priority)); err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		}()
+	}
+	send("first", 0)
+	for {
+		limiter.mu.Lock()
+		active := limiter.active
+		limiter.mu.Unlock()
+		if active == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	send("background", -1)
+	waiting(1)
+	send("normal", 0)
+	waiting(2)
+	send("critical", 1)
+	waiting(3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Do(client, WithLimiter(newRequest(t, "canceled").WithContext(ctx), limiter)); err != context.Canceled {
+		t.Fatalf("expected waiting to be canceled, got %v", err)
+	}
+
+	close(gate)
+	wg.Wait()
+	if len(order) != 4 || order[1] != "/critical" || order[2] != "/normal" || order[3] != "/background" {
+		t.Fatalf("expected requests sent by priority, got %v", order)
+	}
+	if limiter.active != 0 {
+		t.Fatalf("expected all slots released, got %d active", limiter.active)
+	}
+}
@@ -0,0 +1,70 @@
+package reqstrategy
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ProbeCache memoizes outcomes of health check and polling requests for a short time, so
+// many callers asking whether the endpoint is healthy share one recent probe. It is safe for concurrent use
+type ProbeCache struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	probes map[string]*probe
+}
+
+type probe struct {
+	done chan struct{}
+	at   time.Time
+	err  error
+}
+
+// NewProbeCache creates ProbeCache keeping probe results for ttl
+func NewProbeCache(ttl time.Duration) *ProbeCache {
+	return &ProbeCache{ttl: ttl, probes: make(map[string]*probe)}
+}
+
+// Check sends the request with Do and returns validation outcome, unless the probe with the same
+// method and URL was completed within ttl or is in flight, then its outcome is returned instead.
+// Only the outcome is kept, response bodies are discarded
+func (c *ProbeCache) Check(client *http.Client, r *http.Request) error {
+	key := r.Method + " " + r.URL.String()
+
+	c.mu.Lock()
+	p, ok := c.probes[key]
+	if ok {
+		select {
+		case <-p.done:
+			ok = time.Since(p.at) < c.ttl
+		default:
+		}
+	}
+	if !ok {
+		p = &probe{done: make(chan struct{})}
+		c.probes[key] = p
+	}
+	c.mu.Unlock()
+
+	if ok {
+		select {
+		case <-p.done:
+			return p.err
+		case <-r.Context().Done():
+			return r.Context().Err()
+		}
+	}
+
+	resp, err := Do(client, r)
+	if resp != nil && resp.Body != nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	p.err = err
+	p.at = time.Now()
+	close(p.done)
+	return err
+}
@@ -0,0 +1,40 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_ProbeCache_Check(t *testing.T) {
+	var count int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&count, 1)
+		<-time.After(50 * time.Millisecond)
+		return &http.Response{Request: r, StatusCode: 503}, nil
+	})
+
+	cache := NewProbeCache(100 * time.Millisecond)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cache.Check(client, WithStatusRequired(newRequest(t, "health"), 200)); err == nil {
+				t.Error("expected probe to fail")
+			}
+		}()
+	}
+	wg.Wait()
+	if count != 1 {
+		t.Fatalf("expected 1 probe request, got %d", count)
+	}
+
+	<-time.After(100 * time.Millisecond)
+	cache.Check(client, WithStatusRequired(newRequest(t, "health"), 200))
+	if count != 2 {
+		t.Fatalf("expected expired probe to be repeated, got %d requests", count)
+	}
+}
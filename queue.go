@@ -0,0 +1,319 @@
+package reqstrategy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header carrying the key identifying repeated deliveries of the same request
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+var (
+	// ErrQueueFull is returned by Queue.Enqueue when the queue has no capacity left
+	ErrQueueFull = errors.New("queue is full")
+	// ErrQueueClosed is returned by Queue.Enqueue once the queue is shut down
+	ErrQueueClosed = errors.New("queue is closed")
+)
+
+// Delivery defines how hard Queue tries to deliver the request
+type Delivery int
+
+const (
+	// AtLeastOnce retries the request until it is delivered, expired (see WithExpiry) or the queue
+	// is shut down. Server may receive the request more than once, so it is sent with IdempotencyKeyHeader
+	// letting the server detect duplicates. Key is generated unless the request already has one
+	AtLeastOnce Delivery = iota
+	// AtMostOnce makes a single attempt dropping the request if it failed
+	AtMostOnce
+)
+
+// Queue delivers requests in the background with a fixed number of workers. Responses are drained
+// and closed, use Results or WithHook to observe delivery outcomes. Requests sharing the ordering key are delivered
+// strictly in the order they were enqueued, see WithOrderingKey. Queue is safe for concurrent use
+type Queue struct {
+	client    *http.Client
+	intervals []time.Duration
+	items     chan queued
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+
+	mu        sync.RWMutex
+	closed    bool
+	clock     Clock
+	locker    Locker
+	lockTTL   time.Duration
+	lanes     map[string][]queued
+	results   chan DeliveryResult
+	reporting bool
+}
+
+// DeliveryResult is the final outcome of the queued request delivery. Response body is already closed
+type DeliveryResult struct {
+	Request  *http.Request
+	Response *http.Response
+	Err      error
+	Attempts int
+}
+
+type queued struct {
+	request  *http.Request
+	delivery Delivery
+}
+
+// NewQueue starts the queue with the number of workers sending requests and capacity of requests waiting
+// to be sent. Failed AtLeastOnce deliveries are retried after provided intervals, the last interval is repeated
+// for all the following attempts, one second is used if no intervals provided
+func NewQueue(client *http.Client, workers, capacity int, intervals ...time.Duration) *Queue {
+	if len(intervals) == 0 {
+		intervals = []time.Duration{time.Second}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		client:    client,
+		intervals: intervals,
+		items:     make(chan queued, capacity),
+		ctx:       ctx,
+		cancel:    cancel,
+		lanes:     make(map[string][]queued),
+		results:   make(chan DeliveryResult, capacity),
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue schedules the request for delivery with provided semantics
+func (q *Queue) Enqueue(r *http.Request, d Delivery) error {
+	if d == AtLeastOnce && r.Header.Get(IdempotencyKeyHeader) == "" {
+		r = withHeader(r, IdempotencyKeyHeader, NewCallID())
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if _, ok := r.Context().Value(keyClock).(Clock); !ok && q.clock != nil {
+		r = WithClock(r, q.clock)
+	}
+	select {
+	case q.items <- queued{r, d}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// SetClock sets the time source for the requests enqueued afterwards, unless they have their own one,
+// see WithClock. It drives waiting between delivery attempts and request expiry
+func (q *Queue) SetClock(c Clock) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.clock = c
+}
+
+// SetLocker makes every delivery attempt of requests with IdempotencyKeyHeader hold the lock of the key
+// for up to ttl, so queues of service replicas sharing persisted requests do not deliver the same one
+// simultaneously. While another owner holds the lock the attempt waits for the retry interval. Locker
+// errors do not stop deliveries, the attempt is made without the lock then
+func (q *Queue) SetLocker(l Locker, ttl time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.locker, q.lockTTL = l, ttl
+}
+
+// Shutdown stops accepting new requests and waits until the queued ones are delivered. If the context
+// is done first, deliveries in progress are cancelled, the rest is dropped and the context error is returned
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.items)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(q.results)
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		q.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Results returns the channel receiving outcomes of every delivery, requests dropped on shutdown
+// are reported with ErrQueueClosed. Outcomes are sent only after Results was called, and from then on
+// the channel has to be drained, otherwise deliveries stall once its buffer is full.
+// Channel is closed when the queue is shut down
+func (q *Queue) Results() <-chan DeliveryResult {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reporting = true
+	return q.results
+}
+
+// WithOrderingKey puts the request into the Queue lane identified by the key. Requests in the lane are
+// delivered one by one in the order they were enqueued, so a request being retried holds back the following
+// ones with the same key, while requests with other keys proceed in parallel
+func WithOrderingKey(r *http.Request, key string) *http.Request {
+	return withValue(r, keyOrdering, key)
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for item := range q.items {
+		key, ok := item.request.Context().Value(keyOrdering).(string)
+		if !ok {
+			q.process(item)
+			continue
+		}
+		if !q.enter(key, item) {
+			continue
+		}
+		for ok {
+			q.process(item)
+			item, ok = q.next(key)
+		}
+	}
+}
+
+func (q *Queue) process(item queued) {
+	result := DeliveryResult{Request: item.request, Err: ErrQueueClosed}
+	if q.ctx.Err() == nil {
+		result = q.deliver(item.request, item.delivery)
+	}
+
+	q.mu.RLock()
+	reporting := q.reporting
+	q.mu.RUnlock()
+	if reporting {
+		select {
+		case q.results <- result:
+		case <-q.ctx.Done():
+		}
+	}
+}
+
+// enter reports whether the worker becomes the owner of the lane, otherwise the item waits in the lane
+// for the current owner to deliver it
+func (q *Queue) enter(key string, item queued) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if lane, busy := q.lanes[key]; busy {
+		q.lanes[key] = append(lane, item)
+		return false
+	}
+	q.lanes[key] = nil
+	return true
+}
+
+// next returns the next item waiting in the lane, the lane is released when there is none
+func (q *Queue) next(key string) (queued, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	lane := q.lanes[key]
+	if len(lane) == 0 {
+		delete(q.lanes, key)
+		return queued{}, false
+	}
+	q.lanes[key] = lane[1:]
+	return lane[0], true
+}
+
+func (q *Queue) deliver(r *http.Request, d Delivery) DeliveryResult {
+	result := DeliveryResult{Request: r}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-q.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	r, err := rewindable(r.WithContext(ctx), MaxBufferedBody)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	clock := clockOf(r)
+	for attempt := 1; true; attempt++ {
+		req, err := rewind(r, attempt)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		release, err := q.acquire(ctx, clock, r.Header.Get(IdempotencyKeyHeader), attempt)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		resp, err := Do(q.client, withAttempt(req, attempt))
+		if resp != nil && resp.Body != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		release()
+		result.Response, result.Err, result.Attempts = resp, err, attempt
+		if err == nil || d == AtMostOnce || err == ErrExpired || noRetry(r) || !classifierOf(r)(resp, err) {
+			return result
+		}
+
+		select {
+		case <-clock.After(q.interval(attempt)):
+		case <-ctx.Done():
+			result.Err = ctx.Err()
+			return result
+		}
+	}
+	return result
+}
+
+// interval returns the wait after the failed attempt, the last interval is repeated
+func (q *Queue) interval(attempt int) time.Duration {
+	if attempt <= len(q.intervals) {
+		return q.intervals[attempt-1]
+	}
+	return q.intervals[len(q.intervals)-1]
+}
+
+// acquire takes the lock of the delivery attempt of the request with the key, see SetLocker. It waits
+// for the retry interval while the lock is held by another owner, the returned function releases the lock
+func (q *Queue) acquire(ctx context.Context, clock Clock, key string, attempt int) (func(), error) {
+	q.mu.RLock()
+	locker, ttl := q.locker, q.lockTTL
+	q.mu.RUnlock()
+	if locker == nil || key == "" {
+		return func() {}, nil
+	}
+	for {
+		token, ok, err := locker.Acquire(ctx, key, ttl)
+		if err != nil {
+			return func() {}, nil
+		}
+		if ok {
+			return func() { locker.Release(context.Background(), key, token) }, nil
+		}
+		select {
+		case <-clock.After(q.interval(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package reqstrategy
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy/reqstrategytest"
+)
+
+func Test_Queue(t *testing.T) {
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	keys := make(map[string]string)
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[r.URL.Path]++
+		keys[r.URL.Path] = r.Header.Get(IdempotencyKeyHeader)
+		if attempts[r.URL.Path] < 3 || string(body) != "payload" {
+			return &http.Response{Request: r, StatusCode: 503}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	queue := NewQueue(client, 2, 10, time.Millisecond)
+	for _, path := range []string{"/at-least-once", "/at-most-once"} {
+		req, _ := http.NewRequest("POST", "http://localhost"+path, strings.NewReader("payload"))
+		delivery := AtLeastOnce
+		if path == "/at-most-once" {
+			delivery = AtMostOnce
+		}
+		if err := queue.Enqueue(WithStatusRequired(req, 200), delivery); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := queue.Shutdown(ctx); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := queue.Enqueue(newRequest(t), AtMostOnce); err != ErrQueueClosed {
+		t.Fatalf("expected ErrQueueClosed, got %v", err)
+	}
+
+	if attempts["/at-least-once"] != 3 || attempts["/at-most-once"] != 1 {
+		t.Fatalf("expected 3 and 1 attempts, got %v", attempts)
+	}
+	if keys["/at-least-once"] == "" || keys["/at-most-once"] != "" {
+		t.Fatalf("expected idempotency key for at-least-once delivery only, got %v", keys)
+	}
+}
+
+func Test_Queue_WithOrderingKey(t *testing.T) {
+	var mu sync.Mutex
+	var delivered []string
+	failures := map[string]int{"/a1": 2}
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures[r.URL.Path] > 0 {
+			failures[r.URL.Path]--
+			return &http.Response{Request: r, StatusCode: 503}, nil
+		}
+		delivered = append(delivered, r.URL.Path)
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	queue := NewQueue(client, 4, 10, 20*time.Millisecond)
+	for _, path := range []string{"a1", "b1", "a2", "b2", "a3"} {
+		req := WithOrderingKey(WithStatusRequired(newRequest(t, path), 200), path[:1])
+		if err := queue.Enqueue(req, AtLeastOnce); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if err := queue.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	got := strings.Join(delivered, " ")
+	if got != "/b1 /b2 /a1 /a2 /a3" {
+		t.Fatalf(`expected "b" lane to proceed while "a" is retried, got "%s"`, got)
+	}
+}
+
+func Test_Queue_Results(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/fail" {
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	queue := NewQueue(client, 1, 10)
+	results := queue.Results()
+	for _, path := range []string{"ok", "fail"} {
+		if err := queue.Enqueue(WithStatusRequired(newRequest(t, path), 200), AtMostOnce); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	go queue.Shutdown(context.Background())
+
+	var got []string
+	for result := range results {
+		outcome := "delivered"
+		if result.Err != nil {
+			outcome = "failed"
+		}
+		got = append(got, result.Request.URL.Path+" "+outcome)
+	}
+	if strings.Join(got, ", ") != "/ok delivered, /fail failed" {
+		t.Fatalf("unexpected results %v", got)
+	}
+}
+
+func Test_Queue_SetClock(t *testing.T) {
+	var mu sync.Mutex
+	var attempts int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts < 3 {
+			return &http.Response{Request: r, StatusCode: 503}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	start := time.Now()
+	clock := reqstrategytest.NewVirtualClock(start)
+	queue := NewQueue(client, 1, 10, time.Hour)
+	queue.SetClock(clock)
+	if err := queue.Enqueue(WithStatusRequired(newRequest(t), 200), AtLeastOnce); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := queue.Shutdown(ctx); err != nil {
+		t.Fatalf("expected delivery driven by the virtual clock, got %s", err)
+	}
+	if attempts != 3 || clock.Now().Sub(start) != 2*time.Hour {
+		t.Fatalf("expected 3 attempts 2 virtual hours apart, got %d in %s", attempts, clock.Now().Sub(start))
+	}
+}
+
+func Test_Queue_SetLocker(t *testing.T) {
+	var mu sync.Mutex
+	var inFlight, maxInFlight, calls int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		calls++
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	locker := NewMemoryLocker()
+
+	var queues []*Queue
+	for i := 0; i < 2; i++ {
+		q := NewQueue(client, 1, 1, time.Millisecond)
+		q.SetLocker(locker, time.Minute)
+		req := newRequest(t, "orders")
+		req.Header.Set(IdempotencyKeyHeader, "order-1")
+		if err := q.Enqueue(req, AtLeastOnce); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		queues = append(queues, q)
+	}
+	for _, q := range queues {
+		if err := q.Shutdown(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if calls != 2 || maxInFlight != 1 {
+		t.Fatalf("expected 2 deliveries one at a time, got %d with up to %d in flight", calls, maxInFlight)
+	}
+}
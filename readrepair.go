@@ -0,0 +1,44 @@
+package reqstrategy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+)
+
+// StaleAnnotation marks the replica response as stale when set by its validator, see Annotate and ReadRepair
+const StaleAnnotation = "stale"
+
+// ReadRepair reads from the fast replica and, if it failed or its validator annotated the response with
+// StaleAnnotation, re-reads from the authoritative endpoint returning its response instead. Once the
+// authoritative read succeeded, repair builds the write bringing the replica up to date from the response,
+// its body stays available for reading. Repair is sent in background without affecting the caller, use
+// WithHook to see the outcome. It is skipped if repair is <nil> or returns an error or <nil> request
+func ReadRepair(client *http.Client, replica, authoritative *http.Request, repair func(fresh *http.Response) (*http.Request, error)) (*http.Response, error) {
+	resp, err := Do(client, withChildID(withStrategy(replica, "ReadRepair"), 1))
+	if _, stale := Annotation(resp, StaleAnnotation); err == nil && !stale {
+		return resp, nil
+	}
+	closeBody(resp)
+
+	resp, err = Do(client, withChildID(withStrategy(authoritative, "ReadRepair"), 2))
+	if err != nil || repair == nil {
+		return resp, err
+	}
+	body, err := bufferBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	write, err := repair(resp)
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil || write == nil {
+		return resp, nil
+	}
+
+	write = withChildID(withStrategy(write, "ReadRepair"), 3)
+	go func() {
+		resp, _ := Do(client, write)
+		Discard(resp)
+	}()
+	return resp, nil
+}
@@ -0,0 +1,53 @@
+package reqstrategy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_ReadRepair(t *testing.T) {
+	repaired := make(chan string, 1)
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case "/replica":
+			return &http.Response{Request: r, StatusCode: 200, Header: http.Header{"Version": {"1"}}, Body: ioutil.NopCloser(strings.NewReader("old"))}, nil
+		case "/primary":
+			return &http.Response{Request: r, StatusCode: 200, Header: http.Header{"Version": {"2"}}, Body: ioutil.NopCloser(strings.NewReader("new"))}, nil
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		repaired <- r.Method + " " + r.URL.Path + " " + string(body)
+		return &http.Response{Request: r, StatusCode: 204}, nil
+	})
+	replica := func(version string) *http.Request {
+		return WithValidator(newRequest(t, "replica"), func(resp *http.Response) error {
+			if resp.Header.Get("Version") < version {
+				Annotate(resp, StaleAnnotation, "true")
+			}
+			return nil
+		})
+	}
+	repair := func(fresh *http.Response) (*http.Request, error) {
+		return http.NewRequest("PUT", "http://localhost/replica/repair", fresh.Body)
+	}
+
+	resp, err := ReadRepair(client, replica("1"), newRequest(t, "primary"), repair)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "old" {
+		t.Fatalf(`expected fresh replica data "old", got "%s"`, body)
+	}
+
+	resp, err = ReadRepair(client, replica("2"), newRequest(t, "primary"), repair)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if body, _ := ioutil.ReadAll(resp.Body); string(body) != "new" {
+		t.Fatalf(`expected authoritative data "new", got "%s"`, body)
+	}
+	if write := <-repaired; write != "PUT /replica/repair new" {
+		t.Fatalf(`expected the replica repaired, got "%s"`, write)
+	}
+}
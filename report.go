@@ -0,0 +1,138 @@
+package reqstrategy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Report aggregates the attempts made for the requests it is attached to with WithReport and the final
+// outcome of the strategy recorded with Finish. It marshals into a stable JSON document so CLI tools can
+// emit machine-readable run reports. It is safe for concurrent use
+type Report struct {
+	mu       sync.Mutex
+	start    time.Time
+	end      time.Time
+	attempts []Attempt
+	finished bool
+	status   int
+	err      error
+}
+
+// NewReport creates the Report, run duration is counted from now
+func NewReport() *Report {
+	return &Report{start: time.Now()}
+}
+
+// WithReport makes every attempt of the request recorded into the report
+func WithReport(r *http.Request, rep *Report) *http.Request {
+	return WithHook(r, rep.record)
+}
+
+// Finish records the outcome returned by the strategy, pass <nil> response for strategies returning many
+func (rep *Report) Finish(resp *http.Response, err error) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.finished = true
+	rep.end = time.Now()
+	rep.err = err
+	if resp != nil {
+		rep.status = resp.StatusCode
+	}
+}
+
+// Attempts returns the attempts recorded so far
+func (rep *Report) Attempts() []Attempt {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	return append([]Attempt(nil), rep.attempts...)
+}
+
+// Err returns the error the strategy finished with
+func (rep *Report) Err() error {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	return rep.err
+}
+
+func (rep *Report) record(a Attempt) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.attempts = append(rep.attempts, a)
+}
+
+// MarshalJSON encodes the report as
+//
+//	{"outcome": "success", "status": 200, "error": "", "start": "...", "duration_ms": 12.5, "attempts": [...]}
+//
+// outcome is one of "success", "failure" or "pending" if Finish was not called yet
+func (rep *Report) MarshalJSON() ([]byte, error) {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	doc := struct {
+		Outcome  string    `json:"outcome"`
+		Status   int       `json:"status,omitempty"`
+		Error    string    `json:"error,omitempty"`
+		Start    time.Time `json:"start"`
+		Duration float64   `json:"duration_ms"`
+		Attempts []Attempt `json:"attempts"`
+	}{
+		Outcome:  "pending",
+		Start:    rep.start,
+		Duration: milliseconds(time.Since(rep.start)),
+		Attempts: rep.attempts,
+	}
+	if rep.attempts == nil {
+		doc.Attempts = []Attempt{}
+	}
+	if rep.finished {
+		doc.Outcome = "success"
+		doc.Status = rep.status
+		doc.Duration = milliseconds(rep.end.Sub(rep.start))
+		if rep.err != nil {
+			doc.Outcome = "failure"
+			doc.Error = rep.err.Error()
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// MarshalJSON encodes the attempt as
+//
+//	{"call_id": "abc.1", "method": "GET", "url": "...", "status": 200, "error": "", "start": "...", "duration_ms": 12.5}
+//
+// attempts cancelled by the strategy have "canceled": true instead of the error
+func (a Attempt) MarshalJSON() ([]byte, error) {
+	doc := struct {
+		CallID   string    `json:"call_id,omitempty"`
+		Method   string    `json:"method"`
+		URL      string    `json:"url"`
+		Status   int       `json:"status,omitempty"`
+		Error    string    `json:"error,omitempty"`
+		Start    time.Time `json:"start"`
+		Duration float64   `json:"duration_ms"`
+		Canceled bool      `json:"canceled,omitempty"`
+	}{
+		CallID:   a.CallID,
+		Start:    a.Start,
+		Duration: milliseconds(a.Duration),
+		Canceled: a.Canceled,
+	}
+	if a.Request != nil {
+		doc.Method = a.Request.Method
+		doc.URL = a.Request.URL.String()
+	}
+	if a.Response != nil {
+		doc.Status = a.Response.StatusCode
+	}
+	if a.Err != nil && !a.Canceled {
+		doc.Error = a.Err.Error()
+	}
+	return json.Marshal(doc)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,58 @@
+package reqstrategy
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_Report(t *testing.T) {
+	var calls int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return &http.Response{StatusCode: 503, Request: r}, nil
+		}
+		return &http.Response{StatusCode: 200, Request: r}, nil
+	})
+
+	report := NewReport()
+	req := WithReport(WithStatusRequired(newRequest(t), 200), report)
+	report.Finish(Retry(client, req, time.Millisecond))
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var doc struct {
+		Outcome  string
+		Status   int
+		Attempts []struct {
+			Method string
+			Status int
+			Error  string
+		}
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if doc.Outcome != "success" || doc.Status != 200 {
+		t.Fatalf("expected successful outcome with status 200, got %s", data)
+	}
+	if len(doc.Attempts) != 2 || doc.Attempts[0].Status != 503 || doc.Attempts[0].Error == "" || doc.Attempts[1].Method != "GET" {
+		t.Fatalf("unexpected attempts %s", data)
+	}
+}
+
+func Test_Report_pending(t *testing.T) {
+	data, err := json.Marshal(NewReport())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var doc map[string]interface{}
+	json.Unmarshal(data, &doc)
+	if doc["outcome"] != "pending" || len(doc["attempts"].([]interface{})) != 0 {
+		t.Fatalf("unexpected report %s", data)
+	}
+}
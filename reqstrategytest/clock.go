@@ -0,0 +1,36 @@
+package reqstrategytest
+
+import (
+	"sync"
+	"time"
+)
+
+// VirtualClock is reqstrategy.Clock which never waits, every After call moves the virtual time forward
+// and fires immediately. Intended to be used with reqstrategy.WithClock to test strategies waiting between
+// attempts without slowing the tests down. It is safe for concurrent use
+type VirtualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewVirtualClock creates VirtualClock starting at provided time
+func NewVirtualClock(start time.Time) *VirtualClock {
+	return &VirtualClock{now: start}
+}
+
+// Now returns the virtual time
+func (c *VirtualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After advances the virtual time by d and returns the channel with the new time already sent
+func (c *VirtualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
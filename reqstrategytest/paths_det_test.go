@@ -0,0 +1,28 @@
+//go:build reqstrategy_det
+// +build reqstrategy_det
+
+package reqstrategytest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy"
+)
+
+// Test_Paths checks the latency of the reply, Race can not be used as requests run one by one in the deterministic mode
+func Test_Paths(t *testing.T) {
+	transport := Paths(map[string]Reply{
+		"/slow": {Latency: 50 * time.Millisecond},
+	})
+
+	slow, _ := http.NewRequest("GET", "http://localhost/slow", nil)
+	start := time.Now()
+	if _, err := reqstrategy.Do(transport.Client(), reqstrategy.WithStatusRequired(slow, 200)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("expected reply to take at least 50ms, took %s", elapsed)
+	}
+}
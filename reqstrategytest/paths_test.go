@@ -0,0 +1,29 @@
+//go:build !reqstrategy_det
+// +build !reqstrategy_det
+
+package reqstrategytest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy"
+)
+
+func Test_Paths(t *testing.T) {
+	transport := Paths(map[string]Reply{
+		"/slow": {Latency: 100 * time.Millisecond},
+		"/fast": {Latency: 10 * time.Millisecond},
+	})
+
+	slow, _ := http.NewRequest("GET", "http://localhost/slow", nil)
+	fast, _ := http.NewRequest("GET", "http://localhost/fast", nil)
+	resp, err := reqstrategy.Race(transport.Client(), slow, fast)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.Request.URL.Path != "/fast" {
+		t.Fatalf(`expected "/fast" to win, got "%s"`, resp.Request.URL.Path)
+	}
+}
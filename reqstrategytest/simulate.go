@@ -0,0 +1,95 @@
+package reqstrategytest
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Model describes the statistical behavior of the endpoint: latency distribution and
+// probability of replying with 503 Service Unavailable
+type Model struct {
+	Latency   func() time.Duration
+	ErrorRate float64
+}
+
+// ExponentialLatency returns exponentially distributed latency with the mean
+func ExponentialLatency(mean time.Duration) func() time.Duration {
+	return func() time.Duration {
+		return time.Duration(rand.ExpFloat64() * float64(mean))
+	}
+}
+
+// LogNormalLatency returns log-normally distributed latency with the median and sigma shape parameter,
+// a typical model for long-tail service latencies
+func LogNormalLatency(median time.Duration, sigma float64) func() time.Duration {
+	return func() time.Duration {
+		return time.Duration(float64(median) * math.Exp(rand.NormFloat64()*sigma))
+	}
+}
+
+// Report summarises the simulation
+type Report struct {
+	Calls         int
+	Failures      int
+	Requests      int
+	P50           time.Duration
+	P99           time.Duration
+	Amplification float64
+}
+
+// Simulate executes the call n times concurrently against the endpoints modeled by host, requests to unknown
+// hosts get 404. The call is expected to use the provided client with the strategy configuration being tuned
+// and return an error when the logical call failed. No network I/O is done, although latencies are waited for
+// real, so models and strategy delays should be scaled down together to speed the simulation up
+func Simulate(n int, models map[string]Model, call func(client *http.Client) error) Report {
+	transport := Func(func(r *http.Request) Reply {
+		model, ok := models[r.URL.Host]
+		if !ok {
+			return Reply{Status: http.StatusNotFound}
+		}
+		reply := Reply{Status: http.StatusOK}
+		if model.Latency != nil {
+			reply.Latency = model.Latency()
+		}
+		if rand.Float64() < model.ErrorRate {
+			reply.Status = http.StatusServiceUnavailable
+		}
+		return reply
+	})
+	client := transport.Client()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	report := Report{Calls: n}
+	durations := make([]time.Duration, 0, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := call(client)
+			d := time.Since(start)
+
+			mu.Lock()
+			defer mu.Unlock()
+			durations = append(durations, d)
+			if err != nil {
+				report.Failures++
+			}
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	report.Requests = len(transport.Requests())
+	if n > 0 {
+		report.P50 = durations[(n-1)*50/100]
+		report.P99 = durations[(n-1)*99/100]
+		report.Amplification = float64(report.Requests) / float64(n)
+	}
+	return report
+}
@@ -0,0 +1,36 @@
+package reqstrategytest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy"
+)
+
+func Test_Simulate(t *testing.T) {
+	models := map[string]Model{
+		"primary":   {Latency: ExponentialLatency(5 * time.Millisecond), ErrorRate: 0.5},
+		"secondary": {Latency: ExponentialLatency(5 * time.Millisecond)},
+	}
+
+	report := Simulate(100, models, func(client *http.Client) error {
+		primary, _ := http.NewRequest("GET", "http://primary/", nil)
+		secondary, _ := http.NewRequest("GET", "http://secondary/", nil)
+		_, err := reqstrategy.Race(client,
+			reqstrategy.WithStatusRequired(primary, 200),
+			reqstrategy.WithStatusRequired(secondary, 200),
+		)
+		return err
+	})
+
+	if report.Failures != 0 {
+		t.Fatalf("expected no failures, got %d", report.Failures)
+	}
+	if report.Requests != 200 || report.Amplification != 2 {
+		t.Fatalf("expected 200 requests with amplification 2, got %d and %f", report.Requests, report.Amplification)
+	}
+	if report.P50 <= 0 || report.P99 < report.P50 {
+		t.Fatalf("unexpected percentiles P50=%s P99=%s", report.P50, report.P99)
+	}
+}
@@ -0,0 +1,195 @@
+// Package reqstrategytest provides utilities for testing code built on top of reqstrategy:
+// scripted transports replying with canned responses, and assertions on the requests made
+//
+//	transport := reqstrategytest.Sequence(
+//	  reqstrategytest.Reply{Status: 503},
+//	  reqstrategytest.Reply{Status: 200, Body: "ok"},
+//	)
+//	resp, err := reqstrategy.Retry(transport.Client(), req, time.Millisecond)
+//	transport.AssertAttempts(t, 2)
+//	transport.AssertBodiesClosed(t)
+package reqstrategytest
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Reply describes the canned response, or the transport error if Err is set.
+// Latency delays the reply unless the request is cancelled earlier
+type Reply struct {
+	Status  int
+	Header  http.Header
+	Body    string
+	Err     error
+	Latency time.Duration
+}
+
+// Transport is http.RoundTripper replying according to the script. It records received
+// requests and tracks whether returned response bodies were closed. It is safe for concurrent use
+type Transport struct {
+	script func(r *http.Request, n int) Reply
+
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []*body
+}
+
+// Func creates Transport getting replies from f
+func Func(f func(r *http.Request) Reply) *Transport {
+	return &Transport{script: func(r *http.Request, n int) Reply { return f(r) }}
+}
+
+// ErrEmptySequence is the transport error every request fails with if Sequence has no replies
+var ErrEmptySequence = errors.New("reqstrategytest: sequence has no replies")
+
+// Sequence creates Transport replying with provided replies in order, the last one is repeated
+// once the sequence is over. Requests fail with ErrEmptySequence if there are no replies
+func Sequence(replies ...Reply) *Transport {
+	return &Transport{script: func(r *http.Request, n int) Reply {
+		if len(replies) == 0 {
+			return Reply{Err: ErrEmptySequence}
+		}
+		if n >= len(replies) {
+			n = len(replies) - 1
+		}
+		return replies[n]
+	}}
+}
+
+// Paths creates Transport replying by request URL path, 404 is replied for unknown paths
+func Paths(replies map[string]Reply) *Transport {
+	return Func(func(r *http.Request) Reply {
+		if reply, ok := replies[r.URL.Path]; ok {
+			return reply
+		}
+		return Reply{Status: http.StatusNotFound}
+	})
+}
+
+// Client returns http.Client using the transport
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	n := len(t.requests)
+	t.requests = append(t.requests, r)
+	t.mu.Unlock()
+
+	reply := t.script(r, n)
+	if reply.Latency > 0 {
+		timer := time.NewTimer(reply.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+	}
+	if reply.Err != nil {
+		return nil, reply.Err
+	}
+
+	header := reply.Header
+	if header == nil {
+		header = make(http.Header)
+	}
+	b := &body{ReadCloser: ioutil.NopCloser(strings.NewReader(reply.Body)), request: r}
+	t.mu.Lock()
+	t.bodies = append(t.bodies, b)
+	t.mu.Unlock()
+
+	status := reply.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          b,
+		ContentLength: int64(len(reply.Body)),
+		Request:       r,
+	}, nil
+}
+
+// Requests returns the requests received so far in the order of arrival
+func (t *Transport) Requests() []*http.Request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*http.Request(nil), t.requests...)
+}
+
+// AssertAttempts fails the test unless exactly n requests were received
+func (t *Transport) AssertAttempts(tb testing.TB, n int) {
+	tb.Helper()
+	if got := len(t.Requests()); got != n {
+		tb.Fatalf("expected %d requests, got %d", n, got)
+	}
+}
+
+// AssertPaths fails the test unless requests were received for the paths in the same order
+func (t *Transport) AssertPaths(tb testing.TB, paths ...string) {
+	tb.Helper()
+	var got []string
+	for _, r := range t.Requests() {
+		got = append(got, r.URL.Path)
+	}
+	if strings.Join(got, " ") != strings.Join(paths, " ") {
+		tb.Fatalf("expected requests to %v, got %v", paths, got)
+	}
+}
+
+// Unclosed returns the requests whose response bodies were not closed
+func (t *Transport) Unclosed() []*http.Request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var requests []*http.Request
+	for _, b := range t.bodies {
+		if !b.isClosed() {
+			requests = append(requests, b.request)
+		}
+	}
+	return requests
+}
+
+// AssertBodiesClosed fails the test if any of the returned response bodies was not closed
+func (t *Transport) AssertBodiesClosed(tb testing.TB) {
+	tb.Helper()
+	for _, r := range t.Unclosed() {
+		tb.Fatalf("response body of %s %s was not closed", r.Method, r.URL)
+	}
+}
+
+type body struct {
+	io.ReadCloser
+	request *http.Request
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (b *body) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	return b.ReadCloser.Close()
+}
+
+func (b *body) isClosed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
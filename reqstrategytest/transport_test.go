@@ -0,0 +1,59 @@
+package reqstrategytest
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy"
+)
+
+func Test_Sequence(t *testing.T) {
+	transport := Sequence(Reply{Status: 503}, Reply{Status: 200, Body: "ok"})
+
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	resp, err := reqstrategy.Retry(transport.Client(), reqstrategy.WithStatusRequired(req, 200), time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Fatalf(`expected "ok" body, got "%s"`, body)
+	}
+	transport.AssertAttempts(t, 2)
+	transport.AssertPaths(t, "/", "/")
+
+	transport.AssertBodiesClosed(t)
+
+	if _, err := reqstrategy.Do(transport.Client(), req); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if unclosed := transport.Unclosed(); len(unclosed) != 1 {
+		t.Fatalf("expected unclosed body to be reported, got %d", len(unclosed))
+	}
+}
+
+func Test_Sequence_empty(t *testing.T) {
+	transport := Sequence()
+
+	req, _ := http.NewRequest("GET", "http://localhost/", nil)
+	if _, err := reqstrategy.Do(transport.Client(), req); !errors.Is(err, ErrEmptySequence) {
+		t.Fatalf("expected ErrEmptySequence, got %v", err)
+	}
+	transport.AssertAttempts(t, 1)
+}
+
+func Test_Paths_unknown(t *testing.T) {
+	transport := Paths(map[string]Reply{
+		"/known": {},
+	})
+
+	unknown, _ := http.NewRequest("GET", "http://localhost/unknown", nil)
+	if _, err := reqstrategy.Do(transport.Client(), reqstrategy.WithStatusRequired(unknown, 404)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	transport.AssertPaths(t, "/unknown")
+}
@@ -1,13 +1,63 @@
 package reqstrategy
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
 )
 
 type key string
 
-const keyValidators key = "validators"
+const (
+	keyValidators      key = "validators"
+	keyStats           key = "stats"
+	keyClockCorrection key = "clock-correction"
+	keyClock           key = "clock"
+	keyTag             key = "tag"
+	keyBudgets         key = "budgets"
+	keyCompression     key = "compression"
+	keyCallID          key = "call-id"
+	keyHooks           key = "hooks"
+	keyDumps           key = "dumps"
+	keyApproval        key = "approval"
+	keyExpiry          key = "expiry"
+	keyOrdering        key = "ordering-key"
+	keyStorm           key = "storm"
+	keyAttempt         key = "attempt"
+	keyAmplification   key = "amplification"
+	keyKillSwitch      key = "kill-switch"
+	keyAnnotations     key = "annotations"
+	keyPreflight       key = "preflight"
+	keyMaintenance     key = "maintenance"
+	keySwitch          key = "switch"
+	keyStagger         key = "stagger"
+	keyDeduplicator    key = "deduplicator"
+	keyHeaderTimeout   key = "header-timeout"
+	keyBodyTimeout     key = "body-timeout"
+	keyIdleTimeout     key = "idle-timeout"
+	keyLimiter         key = "limiter"
+	keyPriority        key = "priority"
+	keyKeepAlive       key = "keep-alive"
+	keyDelay           key = "delay"
+	keyInvalid         key = "invalid"
+	keyJitter          key = "jitter"
+	keyNoRetry         key = "no-retry"
+	keyNoHedge         key = "no-hedge"
+	keyLeaks           key = "leaks"
+	keyRetryPolicy     key = "retry-policy"
+	keyClassifier      key = "retry-classifier"
+	keyStopped         key = "stopped"
+	keyBackoff         key = "backoff"
+	keyMaxAttempts     key = "max-attempts"
+	keyMaxElapsed      key = "max-elapsed"
+)
 
 type validator = func(r *http.Response) error
 
@@ -17,13 +67,282 @@ type result struct {
 	err      error
 }
 
+type clockCorrection struct {
+	match func(*http.Response) bool
+	sign  func(r *http.Request, now time.Time) (*http.Request, error)
+}
+
+// retry re-signs the request with the server time and sends it again, correction is not repeated.
+// The request body is obtained again with GetBody, ErrBodyNotRewindable is returned if it can not be
+func (c *clockCorrection) retry(client *http.Client, r *http.Request, resp *http.Response) (*http.Response, error) {
+	r, err := rewind(r, 2)
+	if err != nil {
+		return resp, err
+	}
+	var skew time.Duration
+	if stats, ok := r.Context().Value(keyStats).(*Stats); ok {
+		skew = stats.Skew(r.URL.Host)
+	} else if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		skew = time.Until(date)
+	}
+
+	signed, err := c.sign(withValue(r, keyClockCorrection, (*clockCorrection)(nil)), time.Now().Add(skew))
+	if err != nil {
+		return resp, err
+	}
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
+	return roundTrip(client, signed)
+}
+
+// bufferBody reads the whole response body replacing it with the in-memory copy,
+// the copy returns the read error, if any, once the data is over
+func bufferBody(resp *http.Response) ([]byte, error) {
+	if resp.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), &errReader{err}))
+	return body, err
+}
+
+// errReader returns err once the data preceding it is read, io.EOF if err is <nil>
+type errReader struct {
+	err error
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.err == nil {
+		return 0, io.EOF
+	}
+	return 0, r.err
+}
+
+func withValue(r *http.Request, k key, v interface{}) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), k, v))
+}
+
+// withHeader returns a copy of the request with the header set, original request headers are not modified
+func withHeader(r *http.Request, name, value string) *http.Request {
+	r = r.WithContext(r.Context())
+	r.Header = cloneHeader(r.Header)
+	r.Header.Set(name, value)
+	return r
+}
+
+func cloneHeader(h http.Header) http.Header {
+	c := make(http.Header, len(h)+1)
+	for k, v := range h {
+		c[k] = v
+	}
+	return c
+}
+
+// run starts the requests returning the channel receiving their results, closing stop cancels requests in flight
+func run(client *http.Client, requests []*http.Request, stop <-chan struct{}) <-chan result {
+	return runEach(client, requests, repeatStop(stop, len(requests)), nil)
+}
+
+// repeatStop returns n copies of the stop channel for runEach
+func repeatStop(stop <-chan struct{}, n int) []<-chan struct{} {
+	stops := make([]<-chan struct{}, n)
+	for i := range stops {
+		stops[i] = stop
+	}
+	return stops
+}
+
+// runEach is run cancelling every request with its own stop channel, closing hurry sends delayed requests
+// right away, see staggered
+func runEach(client *http.Client, requests []*http.Request, stops []<-chan struct{}, hurry <-chan struct{}) <-chan result {
+	results := make(chan result, len(requests))
+	starts := startTimes(requests)
+	for i, r := range requests {
+		i, r := i, r
+		spawn(func() {
+			if !staggered(r, i, starts[i], stops[i], hurry) {
+				results <- result{i, nil, context.Canceled}
+				return
+			}
+			do(client, r, i, stops[i], results)
+		})
+	}
+	return results
+}
+
+// runLimited is run sending at most limit requests at once in the order they are passed,
+// requests not started before stop is closed are never sent, they fail with context.Canceled
+func runLimited(client *http.Client, requests []*http.Request, limit int, stop <-chan struct{}) <-chan result {
+	results := make(chan result, len(requests))
+	queue := make(chan int, len(requests))
+	for i := range requests {
+		queue <- i
+	}
+	close(queue)
+	if limit < 1 || limit > len(requests) {
+		limit = len(requests)
+	}
+	starts := startTimes(requests)
+	for w := 0; w < limit; w++ {
+		spawn(func() {
+			for i := range queue {
+				select {
+				case <-stop:
+					results <- result{i, nil, context.Canceled}
+					continue
+				default:
+				}
+				if !staggered(requests[i], i, starts[i], stop, nil) {
+					results <- result{i, nil, context.Canceled}
+					continue
+				}
+				do(client, requests[i], i, stop, results)
+			}
+		})
+	}
+	return results
+}
+
+// do sends the request cancelling it once stop is closed while it is in flight. Once the response succeeded
+// stop no longer applies, its context is kept until the body is closed so it can be streamed, strategies
+// close bodies of the responses they do not return. Attempts cancelled with stop are reported as Attempt.Canceled
 func do(client *http.Client, r *http.Request, order int, stop <-chan struct{}, results chan<- result) {
 	ctx, cancel := context.WithCancel(r.Context())
-	defer cancel()
+	stopped := new(int32)
+	succeeded := make(chan struct{})
 	go func() {
-		<-stop
-		cancel()
+		select {
+		case <-stop:
+			atomic.StoreInt32(stopped, 1)
+			cancel()
+		case <-succeeded:
+		case <-ctx.Done():
+		}
 	}()
-	response, err := Do(client, r.WithContext(ctx))
+	response, err := Do(client, withStopped(withChildID(r.WithContext(ctx), order+1), stopped))
+	if err != nil {
+		cancel()
+	} else {
+		close(succeeded)
+		cancelOnClose(response, cancel)
+	}
 	results <- result{order, response, err}
 }
+
+// closeResults closes bodies of the next n results in background, for the results the strategy does not return
+func closeResults(results <-chan result, n int) {
+	if n <= 0 {
+		return
+	}
+	go func() {
+		for ; n > 0; n-- {
+			closeBody((<-results).response)
+		}
+	}()
+}
+
+// retryLimits caps the attempts of the retry loop, zero values do not limit it
+type retryLimits struct {
+	attempts int
+	elapsed  time.Duration
+}
+
+// limitsOf returns the limits the strategy retrying the request applies, see WithMaxAttempts and WithMaxElapsed.
+// They are not applied to retries made by Do, see WithRetryPolicy
+func limitsOf(r *http.Request) retryLimits {
+	var limits retryLimits
+	limits.attempts, _ = r.Context().Value(keyMaxAttempts).(int)
+	limits.elapsed, _ = r.Context().Value(keyMaxElapsed).(time.Duration)
+	return limits
+}
+
+// retry calls attempt until it succeeds or intervals are over, r provides the context, clock, expiry and limits.
+// Bodies of failed responses are closed unless the response is returned
+func retry(r *http.Request, intervals []time.Duration, attempt func(n int) (*http.Response, error)) (*http.Response, error) {
+	return retryBackoff(r, Intervals(intervals...), nil, limitsOf(r), attempt)
+}
+
+// retryBackoff is retry waiting between attempts as the backoff tells, only failures retryable reports
+// are retried, the request classifier is used if retryable is <nil>, see WithRetryClassifier. Attempts
+// are limited with limits
+func retryBackoff(r *http.Request, backoff Backoff, retryable func(*http.Response, error) bool, limits retryLimits, attempt func(n int) (*http.Response, error)) (*http.Response, error) {
+	ctx := r.Context()
+	if retryable == nil {
+		retryable = classifierOf(r)
+	}
+	clock := clockOf(r)
+	jitter, _ := ctx.Value(keyJitter).(Jitter)
+	start := clock.Now()
+	var prev time.Duration
+	for n := 1; true; n++ {
+		response, err := attempt(n)
+		if err == nil {
+			return response, nil
+		}
+		wait, ok := backoff.Next(n)
+		if !ok || n == limits.attempts || noRetry(r) || !retryable(response, err) {
+			return response, err
+		}
+		wait = jitter.apply(wait, prev)
+		prev = wait
+		if limits.elapsed > 0 && clock.Now().Add(wait).Sub(start) > limits.elapsed {
+			return response, err
+		}
+		closeBody(response)
+		if expired(r, wait) {
+			return nil, ErrExpired
+		}
+		select {
+		case <-clock.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, fmt.Errorf("retry loop failed")
+}
+
+// retarget returns a copy of the request sent to the scheme and host of the base URL,
+// the body of the copy is obtained with GetBody
+func retarget(r *http.Request, base string) (*http.Request, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	target := *r.URL
+	target.Scheme = u.Scheme
+	target.Host = u.Host
+
+	c := r.WithContext(r.Context())
+	c.URL = &target
+	c.Host = ""
+	if r.Body != nil && r.Body != http.NoBody {
+		if r.GetBody == nil {
+			return nil, errors.New("request body can not be copied without GetBody")
+		}
+		if c.Body, err = r.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// cancelOnClose defers cancel until the response body is closed, cancel is called right away if there is no body
+func cancelOnClose(resp *http.Response, cancel context.CancelFunc) {
+	if resp == nil || resp.Body == nil {
+		cancel()
+		return
+	}
+	resp.Body = &cancelBody{resp.Body, cancel}
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
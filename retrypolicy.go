@@ -0,0 +1,114 @@
+package reqstrategy
+
+import (
+	"math"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy describes the exponential backoff of automatic retries made by Do, so any strategy sending
+// the request retries it, see WithRetryPolicy. It is the Backoff as well, see RetryBackoff
+type RetryPolicy struct {
+	// MaxAttempts limits the number of attempts including the first one, not limited if zero
+	MaxAttempts int
+	// InitialInterval is the wait before the first retry
+	InitialInterval time.Duration
+	// Multiplier grows the interval after every retry, 2 if zero
+	Multiplier float64
+	// MaxInterval caps the interval, not capped if zero
+	MaxInterval time.Duration
+	// Jitter randomizes intervals, see WithJitter
+	Jitter Jitter
+	// MaxElapsed limits the time of all attempts and waits between them, not limited if zero.
+	// Retry which would start past the limit is not made
+	MaxElapsed time.Duration
+	// Classifier tells whether the failed attempt is worth retrying, the request classifier is used
+	// if it is <nil>, see WithRetryClassifier
+	Classifier func(resp *http.Response, err error) bool
+}
+
+// WithRetryPolicy makes Do retry the request according to the policy, whichever strategy sends it.
+// Retries are made within a single attempt of retrying strategies like Retry, so attempts multiply.
+// Policy without MaxAttempts and MaxElapsed retries until the request context is done
+func WithRetryPolicy(r *http.Request, p RetryPolicy) *http.Request {
+	return withValue(r, keyRetryPolicy, p)
+}
+
+// Next returns the interval before the next attempt once the attempt n failed, MaxElapsed is not taken into account
+func (p RetryPolicy) Next(n int) (time.Duration, bool) {
+	if p.MaxAttempts > 0 && n >= p.MaxAttempts {
+		return 0, false
+	}
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	return capped(float64(p.InitialInterval)*math.Pow(multiplier, float64(n-1)), p.MaxInterval), true
+}
+
+// do sends the request retrying it according to the policy
+func (p RetryPolicy) do(client *http.Client, request *http.Request) (*http.Response, error) {
+	if noRetry(request) {
+		return send(client, request)
+	}
+	request, err := rewindable(request, MaxBufferedBody)
+	if err != nil {
+		return nil, err
+	}
+	if p.Jitter != JitterNone {
+		request = WithJitter(request, p.Jitter)
+	}
+	clock := clockOf(request)
+	start := clock.Now()
+	backoff := BackoffFunc(func(n int) (time.Duration, bool) {
+		wait, ok := p.Next(n)
+		if p.MaxElapsed > 0 && clock.Now().Add(wait).Sub(start) > p.MaxElapsed {
+			return 0, false
+		}
+		return wait, ok
+	})
+	return retryBackoff(request, backoff, p.Classifier, retryLimits{}, func(attempt int) (*http.Response, error) {
+		// the first attempt keeps the attempt metadata of the strategy which sent the request
+		if attempt == 1 {
+			return send(client, request)
+		}
+		r, err := rewind(request, attempt)
+		if err != nil {
+			return nil, err
+		}
+		return send(client, withAttempt(r, attempt))
+	})
+}
+
+// WithRetryClassifier sets the function telling whether the failed attempt of the request is worth retrying,
+// so failures which fail validation but would fail again, like 400 or 404, are not retried. It is honored by
+// all retrying strategies, all failures are retried by default, see RetryStatuses
+func WithRetryClassifier(r *http.Request, retryable func(resp *http.Response, err error) bool) *http.Request {
+	return withValue(r, keyClassifier, retryable)
+}
+
+// RetryStatuses is the retry classifier retrying transport failures, i.e. when there is no response,
+// and responses with listed statuses, e.g. RetryStatuses(429, 500, 502, 503, 504)
+func RetryStatuses(codes ...int) func(resp *http.Response, err error) bool {
+	return func(resp *http.Response, err error) bool {
+		if resp == nil {
+			return true
+		}
+		for _, code := range codes {
+			if resp.StatusCode == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// classifierOf returns the retry classifier of the request, the one retrying all failures if there is none
+func classifierOf(r *http.Request) func(*http.Response, error) bool {
+	if retryable, ok := r.Context().Value(keyClassifier).(func(*http.Response, error) bool); ok {
+		return retryable
+	}
+	return func(*http.Response, error) bool {
+		return true
+	}
+}
@@ -0,0 +1,140 @@
+package reqstrategy
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/syavorsky/reqstrategy/reqstrategytest"
+)
+
+func Test_RetryPolicy_Next(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, InitialInterval: time.Second, MaxInterval: 3 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}
+	if actual := waits(p, 10); fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func Test_WithRetryPolicy(t *testing.T) {
+	var statuses []int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		status := statuses[0]
+		statuses = statuses[1:]
+		return &http.Response{Request: r, StatusCode: status}, nil
+	})
+	clock := reqstrategytest.NewVirtualClock(time.Now())
+	var attempts int
+	newPolicyRequest := func(p RetryPolicy) *http.Request {
+		r := WithClock(WithStatusRequired(newRequest(t), 200), clock)
+		return WithHook(WithRetryPolicy(r, p), func(Attempt) {
+			attempts++
+		})
+	}
+	transient := func(resp *http.Response, err error) bool {
+		return resp == nil || resp.StatusCode >= 500
+	}
+
+	cases := []struct {
+		name     string
+		policy   RetryPolicy
+		statuses []int
+		success  bool
+		attempts int
+		elapsed  time.Duration
+	}{
+		{"retried", RetryPolicy{MaxAttempts: 5, InitialInterval: time.Second}, []int{503, 503, 200}, true, 3, 3 * time.Second},
+		{"max attempts", RetryPolicy{MaxAttempts: 2, InitialInterval: time.Second}, []int{503, 503, 200}, false, 2, time.Second},
+		{"max elapsed", RetryPolicy{InitialInterval: time.Second, Multiplier: 1, MaxElapsed: 2500 * time.Millisecond}, []int{503, 503, 503, 200}, false, 3, 2 * time.Second},
+		{"not retryable", RetryPolicy{MaxAttempts: 5, InitialInterval: time.Second, Classifier: transient}, []int{404, 200}, false, 1, 0},
+	}
+	for _, c := range cases {
+		statuses, attempts = c.statuses, 0
+		start := clock.Now()
+		_, err := Do(client, newPolicyRequest(c.policy))
+		if (err == nil) != c.success || attempts != c.attempts || clock.Now().Sub(start) != c.elapsed {
+			t.Errorf("%s: expected success %t after %d attempts and %s, got %v after %d attempts and %s",
+				c.name, c.success, c.attempts, c.elapsed, err, attempts, clock.Now().Sub(start))
+		}
+	}
+
+	statuses, attempts = []int{503, 200}, 0
+	if _, err := Do(client, WithNoRetry(newPolicyRequest(RetryPolicy{InitialInterval: time.Second}))); err == nil || attempts != 1 {
+		t.Errorf("expected request marked with WithNoRetry sent once, got %d attempts", attempts)
+	}
+}
+
+func Test_WithRetryPolicy_strategyLimits(t *testing.T) {
+	statuses := []int{503, 503, 200}
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		status := statuses[0]
+		statuses = statuses[1:]
+		return &http.Response{Request: r, StatusCode: status}, nil
+	})
+	clock := reqstrategytest.NewVirtualClock(time.Now())
+	req := WithRetryPolicy(WithClock(WithStatusRequired(newRequest(t), 200), clock), RetryPolicy{MaxAttempts: 5, InitialInterval: time.Second})
+	req = WithMaxElapsed(WithMaxAttempts(req, 1), time.Millisecond)
+
+	if _, err := Retry(client, req, time.Second); err != nil || len(statuses) != 0 {
+		t.Fatalf("expected the policy retries not limited by the strategy, got %v with %d statuses left", err, len(statuses))
+	}
+}
+
+func Test_WithRetryClassifier(t *testing.T) {
+	var statuses []int
+	var calls int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		calls++
+		status := statuses[0]
+		statuses = statuses[1:]
+		if status == 0 {
+			return nil, fmt.Errorf("connection reset")
+		}
+		return &http.Response{Request: r, StatusCode: status}, nil
+	})
+	req := WithRetryClassifier(WithStatusRequired(newRequest(t), 200), RetryStatuses(502, 503))
+
+	statuses, calls = []int{0, 503, 200}, 0
+	if _, err := Retry(client, req, time.Millisecond, time.Millisecond); err != nil || calls != 3 {
+		t.Fatalf("expected transport failure and 503 retried, got %v after %d calls", err, calls)
+	}
+
+	statuses, calls = []int{404, 200}, 0
+	resp, err := Retry(client, req, time.Millisecond, time.Millisecond)
+	if err == nil || calls != 1 || resp.StatusCode != 404 {
+		t.Fatalf("expected 404 to fail right away, got %v after %d calls", err, calls)
+	}
+}
+
+func Test_WithRetryPolicy_fanOut(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[r.URL.Path]++
+		if r.URL.Path == "/flaky" && calls[r.URL.Path] == 1 {
+			return nil, fmt.Errorf("connection reset")
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	clock := reqstrategytest.NewVirtualClock(time.Now())
+	policy := RetryPolicy{MaxAttempts: 3, InitialInterval: time.Second}
+	flaky := WithRetryPolicy(WithClock(newRequest(t, "flaky"), clock), policy)
+
+	responses, err := All(client, newRequest(t, "stable"), flaky)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if responses[1].Request.URL.Path != "/flaky" || calls["/flaky"] != 2 || calls["/stable"] != 1 {
+		t.Fatalf("expected only the flaky leg retried, got %v", calls)
+	}
+
+	calls = make(map[string]int)
+	responses, err = Some(client, flaky, WithRetryPolicy(newRequest(t, "stable"), policy))
+	if err != nil || responses[0] == nil || calls["/flaky"] != 2 {
+		t.Fatalf("expected the flaky leg retried within Some, got %v after %v", err, calls)
+	}
+}
@@ -0,0 +1,80 @@
+package reqstrategy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// ErrBodyNotRewindable is returned when the request has to be sent again but its body can not be obtained
+// again with GetBody, and it was too large to be buffered, see MaxBufferedBody
+var ErrBodyNotRewindable = errors.New("request body can not be sent again without GetBody")
+
+// MaxBufferedBody is the size of the request body without GetBody buffered in memory by retrying strategies,
+// so the request can be sent again. Larger bodies are streamed and only the first attempt can be made
+const MaxBufferedBody = 1 << 20
+
+// rewindable returns the request which body can be obtained again with GetBody, the body without GetBody is
+// buffered if it is not larger than limit, any size is buffered if limit is negative. Larger body is left
+// to be sent once
+func rewindable(r *http.Request, limit int64) (*http.Request, error) {
+	if r.Body == nil || r.Body == http.NoBody || r.GetBody != nil {
+		return r, nil
+	}
+	source := r.Body
+	reader := io.Reader(source)
+	if limit >= 0 {
+		reader = io.LimitReader(source, limit+1)
+	}
+	body, err := ioutil.ReadAll(reader)
+	if err != nil {
+		source.Close()
+		return nil, err
+	}
+	r = r.WithContext(r.Context())
+	if limit >= 0 && int64(len(body)) > limit {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), source), source}
+		return r, nil
+	}
+	source.Close()
+	r.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	r.Body, _ = r.GetBody()
+	return r, nil
+}
+
+// rewind returns the request with a fresh body for the repeated attempt
+func rewind(r *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || r.Body == nil || r.Body == http.NoBody {
+		return r, nil
+	}
+	if r.GetBody == nil {
+		return nil, ErrBodyNotRewindable
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r = r.WithContext(r.Context())
+	r.Body = body
+	return r, nil
+}
+
+// rewindables makes every request rewindable, see rewindable
+func rewindables(requests []*http.Request) ([]*http.Request, error) {
+	result := make([]*http.Request, len(requests))
+	for i, r := range requests {
+		r, err := rewindable(r, MaxBufferedBody)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = r
+	}
+	return result, nil
+}
@@ -0,0 +1,56 @@
+package reqstrategy
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Retry_bufferedBody(t *testing.T) {
+	var bodies []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		status := 503
+		if len(bodies) == 3 {
+			status = 200
+		}
+		return &http.Response{Request: r, StatusCode: status, Body: http.NoBody}, nil
+	})
+
+	req, _ := http.NewRequest("POST", "http://localhost/orders", ioutil.NopCloser(strings.NewReader("data")))
+	req = WithStatusRequired(req, 200)
+	if _, err := Retry(client, req, time.Millisecond, time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if strings.Join(bodies, ",") != "data,data,data" {
+		t.Fatalf("expected the same body in every attempt, got %q", bodies)
+	}
+}
+
+func Test_Retry_bodyNotRewindable(t *testing.T) {
+	var sent int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if len(body) != MaxBufferedBody+1 {
+			t.Errorf("expected the whole body sent, got %d bytes", len(body))
+		}
+		sent++
+		return &http.Response{Request: r, StatusCode: 503, Body: http.NoBody}, nil
+	})
+
+	body := ioutil.NopCloser(bytes.NewReader(make([]byte, MaxBufferedBody+1)))
+	req, _ := http.NewRequest("POST", "http://localhost/upload", body)
+	req = WithStatusRequired(req, 200)
+	_, err := Retry(client, req, time.Millisecond)
+	if !errors.Is(err, ErrBodyNotRewindable) {
+		t.Fatalf("expected ErrBodyNotRewindable, got %v", err)
+	}
+	if sent != 1 {
+		t.Fatalf("expected 1 attempt, got %d", sent)
+	}
+}
@@ -0,0 +1,96 @@
+package reqstrategy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Ring keeps redacted records of the last attempts made for the requests it is attached to with WithRing,
+// and optionally their dumps, so the last outbound calls can be inspected during an incident without
+// logging all of them. It is an AuditSink and an http.Handler serving the records as JSON on a debug
+// endpoint. It is safe for concurrent use
+type Ring struct {
+	size   int
+	redact []string
+	dumps  *DumpBundle
+
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+// NewRing creates Ring keeping the last size attempts. Dumping reads whole request and response bodies
+// into memory, see DumpBundle. Values of listed headers are redacted in addition to Authorization,
+// Proxy-Authorization, Cookie and Set-Cookie
+func NewRing(size int, dumps bool, redact ...string) *Ring {
+	ring := &Ring{size: size, redact: redact}
+	if dumps {
+		ring.dumps = NewDumpBundle(redact...)
+		ring.dumps.limit = size
+	}
+	return ring
+}
+
+// WithRing makes every attempt of the request recorded into the ring. Attempts are not dumped
+// if the request already has a DumpBundle attached, see WithDumps
+func WithRing(r *http.Request, ring *Ring) *http.Request {
+	if _, ok := r.Context().Value(keyDumps).(*DumpBundle); !ok && ring.dumps != nil {
+		r = WithDumps(r, ring.dumps)
+	}
+	return WithAudit(r, ring, "", ring.redact...)
+}
+
+// Record adds the record dropping the oldest one once the ring is full
+func (ring *Ring) Record(r AuditRecord) {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	ring.records = append(ring.records, r)
+	if len(ring.records) > ring.size {
+		ring.records = ring.records[len(ring.records)-ring.size:]
+	}
+}
+
+// Records returns the records kept, oldest first
+func (ring *Ring) Records() []AuditRecord {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+	return append([]AuditRecord(nil), ring.records...)
+}
+
+// Dumps returns the dumps kept, oldest first, <nil> if the ring does not keep dumps
+func (ring *Ring) Dumps() []Dump {
+	if ring.dumps == nil {
+		return nil
+	}
+	return ring.dumps.Dumps()
+}
+
+// ServeHTTP responds with the records and dumps kept as
+//
+//	{"attempts": [...], "dumps": [{"call_id": "abc.1", "request": "GET / HTTP/1.1...", "response": "...", "error": ""}]}
+//
+// see AuditRecord.MarshalJSON for the attempt format
+func (ring *Ring) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	type dump struct {
+		CallID   string `json:"call_id,omitempty"`
+		Request  string `json:"request"`
+		Response string `json:"response,omitempty"`
+		Error    string `json:"error,omitempty"`
+	}
+	doc := struct {
+		Attempts []AuditRecord `json:"attempts"`
+		Dumps    []dump        `json:"dumps,omitempty"`
+	}{Attempts: ring.Records()}
+	if doc.Attempts == nil {
+		doc.Attempts = []AuditRecord{}
+	}
+	for _, d := range ring.Dumps() {
+		item := dump{CallID: d.CallID, Request: string(d.Request), Response: string(d.Response)}
+		if d.Err != nil {
+			item.Error = d.Err.Error()
+		}
+		doc.Dumps = append(doc.Dumps, item)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(doc)
+}
@@ -0,0 +1,52 @@
+package reqstrategy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_Ring(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200, Body: http.NoBody}, nil
+	})
+	ring := NewRing(2, true, "X-Api-Key")
+	for i := 0; i < 3; i++ {
+		r := WithCallID(newRequest(t, strconv.Itoa(i)), "call"+strconv.Itoa(i))
+		r.Header.Set("X-Api-Key", "secret")
+		if _, err := Do(client, WithRing(r, ring)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	records := ring.Records()
+	if len(records) != 2 || records[0].CallID != "call1" || records[1].CallID != "call2" {
+		t.Fatalf("expected the last 2 attempts kept, got %v", records)
+	}
+	dumps := ring.Dumps()
+	if len(dumps) != 2 || dumps[0].CallID != "call1" || strings.Contains(string(dumps[1].Request), "secret") {
+		t.Fatalf("expected the last 2 redacted dumps kept, got %v", dumps)
+	}
+
+	w := httptest.NewRecorder()
+	ring.ServeHTTP(w, httptest.NewRequest("GET", "/debug/calls", nil))
+	var doc struct {
+		Attempts []struct {
+			CallID string      `json:"call_id"`
+			Status int         `json:"status"`
+			Header http.Header `json:"header"`
+		} `json:"attempts"`
+		Dumps []struct {
+			Request string `json:"request"`
+		} `json:"dumps"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(doc.Attempts) != 2 || doc.Attempts[1].Status != 200 || doc.Attempts[1].Header.Get("X-Api-Key") != "[REDACTED]" || len(doc.Dumps) != 2 {
+		t.Fatalf("unexpected response %s", w.Body.String())
+	}
+}
@@ -0,0 +1,256 @@
+package reqstrategy
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"path"
+	"sync"
+)
+
+// Rule describes requests by method, URL path pattern (see path.Match) and tag (see WithTag).
+// Empty fields match any request
+type Rule struct {
+	Methods []string
+	Path    string
+	Tag     string
+}
+
+// Match reports whether the request satisfies the rule
+func (rule Rule) Match(r *http.Request) bool {
+	if len(rule.Methods) != 0 && !containsString(rule.Methods, r.Method) {
+		return false
+	}
+	if rule.Path != "" {
+		if ok, _ := path.Match(rule.Path, r.URL.Path); !ok {
+			return false
+		}
+	}
+	if rule.Tag != "" {
+		if tag, _ := r.Context().Value(keyTag).(string); tag != rule.Tag {
+			return false
+		}
+	}
+	return true
+}
+
+// Runner is a single entry point executing requests with the strategy picked by the rules, e.g.
+//
+//	runner := &Runner{Client: client}
+//	runner.Handle(Rule{Methods: []string{"GET"}}, func(c *http.Client, r *http.Request) (*http.Response, error) {
+//	  return Race(c, r, replica(r))
+//	})
+//	runner.Handle(Rule{Methods: []string{"POST"}}, func(c *http.Client, r *http.Request) (*http.Response, error) {
+//	  return Retry(c, r, time.Second, 2*time.Second)
+//	})
+//	resp, err := runner.Execute(req)
+//
+// Requests named with WithCallName are executed with the policy registered for the name, if any, see SetCallPolicy.
+// Otherwise rules are checked in the order they were added, requests matching none are sent with Do.
+// Policies can be overridden for a while, see WithTemporaryPolicy.
+// Runner is safe for concurrent use
+type Runner struct {
+	Client *http.Client
+
+	mu         sync.RWMutex
+	routes     []route
+	calls      map[string]Policy
+	overrides  []temporaryPolicy
+	sampler    *Sampler
+	tls        map[string]*tls.Config
+	certs      map[string]CertificateProvider
+	transports map[string]http.RoundTripper
+}
+
+type route struct {
+	rule     Rule
+	strategy func(client *http.Client, r *http.Request) (*http.Response, error)
+}
+
+type temporaryPolicy struct {
+	ctx    context.Context
+	policy Policy
+}
+
+// Handle registers the strategy for requests matching the rule
+func (rn *Runner) Handle(rule Rule, strategy func(client *http.Client, r *http.Request) (*http.Response, error)) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	rn.routes = append(rn.routes, route{rule, strategy})
+}
+
+// SetCallPolicy registers the policy for requests named with WithCallName, it takes precedence over the rules
+func (rn *Runner) SetCallPolicy(name string, p Policy) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.calls == nil {
+		rn.calls = make(map[string]Policy)
+	}
+	rn.calls[name] = p
+}
+
+// WithTemporaryPolicy applies the override to the calls executed until ctx is done, e.g. more aggressive
+// retries during the deploy of a downstream service. Fields set in the override replace the ones of call
+// policies, see SetCallPolicy, requests without a call policy are executed with the override instead of
+// the rules. The latest override still in effect is applied
+func (rn *Runner) WithTemporaryPolicy(ctx context.Context, override Policy) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	active := rn.overrides[:0]
+	for _, o := range rn.overrides {
+		if o.ctx.Err() == nil {
+			active = append(active, o)
+		}
+	}
+	rn.overrides = append(active, temporaryPolicy{ctx, override})
+}
+
+// override returns the latest override in effect, must be called holding the lock
+func (rn *Runner) override() (Policy, bool) {
+	for i := len(rn.overrides) - 1; i >= 0; i-- {
+		if o := rn.overrides[i]; o.ctx.Err() == nil {
+			return o.policy, true
+		}
+	}
+	return Policy{}, false
+}
+
+// SetSampler makes the Runner record a fraction of calls with the sampler, <nil> stops the sampling
+func (rn *Runner) SetSampler(s *Sampler) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	rn.sampler = s
+}
+
+// SetTLS sets TLS configuration, e.g. custom CAs or client certificates, for requests to the host.
+// Host is matched against request URL host with port first, then without the port. It requires
+// Client.Transport to be *http.Transport or <nil>, otherwise the transport is used as is
+func (rn *Runner) SetTLS(host string, cfg *tls.Config) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.tls == nil {
+		rn.tls = make(map[string]*tls.Config)
+	}
+	rn.tls[host] = cfg
+	rn.transports = nil
+}
+
+// SetCertificateProvider makes requests to the host present the client certificate returned by provider,
+// see RotatingTransport. It can be combined with SetTLS for the same host
+func (rn *Runner) SetCertificateProvider(host string, provider CertificateProvider) {
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if rn.certs == nil {
+		rn.certs = make(map[string]CertificateProvider)
+	}
+	rn.certs[host] = provider
+	rn.transports = nil
+}
+
+// Execute runs the request with the strategy of the first matching rule
+func (rn *Runner) Execute(r *http.Request) (*http.Response, error) {
+	client := http.DefaultClient
+	if rn.Client != nil {
+		client = rn.Client
+	}
+	rn.mu.RLock()
+	profiles := len(rn.tls) + len(rn.certs)
+	sampler := rn.sampler
+	rn.mu.RUnlock()
+	if profiles != 0 {
+		c := *client
+		c.Transport = runnerTransport{rn}
+		client = &c
+	}
+	if sampler != nil {
+		var recorded func()
+		r, recorded = sampler.sample(r)
+		defer recorded()
+	}
+	return rn.strategy(r)(client, r)
+}
+
+func (rn *Runner) strategy(r *http.Request) func(client *http.Client, r *http.Request) (*http.Response, error) {
+	rn.mu.RLock()
+	defer rn.mu.RUnlock()
+	override, overridden := rn.override()
+	if name, ok := r.Context().Value(CallNameKey).(string); ok {
+		if p, ok := rn.calls[name]; ok {
+			if overridden {
+				p = p.merge(override)
+			}
+			return p.Execute
+		}
+	}
+	if overridden {
+		return override.Execute
+	}
+	for _, route := range rn.routes {
+		if route.rule.Match(r) {
+			return route.strategy
+		}
+	}
+	return Do
+}
+
+// transport returns the transport for the request taking TLS profiles into account
+func (rn *Runner) transport(r *http.Request) http.RoundTripper {
+	base := http.DefaultTransport
+	if rn.Client != nil && rn.Client.Transport != nil {
+		base = rn.Client.Transport
+	}
+
+	rn.mu.RLock()
+	host := r.URL.Host
+	if rn.tls[host] == nil && rn.certs[host] == nil {
+		host = r.URL.Hostname()
+	}
+	cfg, provider := rn.tls[host], rn.certs[host]
+	transport, cached := rn.transports[host]
+	rn.mu.RUnlock()
+
+	t, configurable := base.(*http.Transport)
+	if (cfg == nil && provider == nil) || !configurable {
+		return base
+	}
+	if cached {
+		return transport
+	}
+
+	rn.mu.Lock()
+	defer rn.mu.Unlock()
+	if transport, cached := rn.transports[host]; cached {
+		return transport
+	}
+	if rn.transports == nil {
+		rn.transports = make(map[string]http.RoundTripper)
+	}
+	t = t.Clone()
+	if cfg != nil {
+		t.TLSClientConfig = cfg
+	}
+	transport = t
+	if provider != nil {
+		transport = RotatingTransport(t, provider)
+	}
+	rn.transports[host] = transport
+	return transport
+}
+
+// runnerTransport sends requests through the transport configured for the host
+type runnerTransport struct {
+	rn *Runner
+}
+
+func (t runnerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return t.rn.transport(r).RoundTrip(r)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package reqstrategy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Runner_Execute(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	var matched []string
+	runner := &Runner{Client: client}
+	runner.Handle(Rule{Methods: []string{"POST"}, Path: "/users/*"}, func(c *http.Client, r *http.Request) (*http.Response, error) {
+		matched = append(matched, "post")
+		return Do(c, r)
+	})
+	runner.Handle(Rule{Tag: "upload"}, func(c *http.Client, r *http.Request) (*http.Response, error) {
+		matched = append(matched, "upload")
+		return Do(c, r)
+	})
+
+	post, _ := http.NewRequest("POST", "http://localhost/users/1", nil)
+	for _, req := range []*http.Request{post, WithTag(newRequest(t, "files"), "upload"), newRequest(t, "users", "1")} {
+		if _, err := runner.Execute(req); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if len(matched) != 2 || matched[0] != "post" || matched[1] != "upload" {
+		t.Fatalf(`expected "post" and "upload" strategies to be used, got %v`, matched)
+	}
+}
+
+func Test_Runner_SetTLS(t *testing.T) {
+	internal := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer internal.Close()
+	public := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer public.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(internal.Certificate())
+	runner := &Runner{Client: &http.Client{Transport: &http.Transport{}}}
+	runner.SetTLS(strings.TrimPrefix(internal.URL, "https://"), &tls.Config{RootCAs: pool})
+
+	req, _ := http.NewRequest("GET", internal.URL, nil)
+	resp, err := runner.Execute(WithStatusRequired(req, 200))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resp.Body.Close()
+
+	req, _ = http.NewRequest("GET", public.URL, nil)
+	if _, err := runner.Execute(req); err == nil {
+		t.Fatal("expected certificate of the host without TLS profile to be rejected")
+	}
+}
+
+func Test_Runner_SetCallPolicy(t *testing.T) {
+	var calls int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		calls++
+		if calls == 1 {
+			return &http.Response{Request: r, StatusCode: 503}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	runner := &Runner{Client: client}
+	runner.SetCallPolicy("getUserProfile", Policy{Statuses: []int{200}, Intervals: []time.Duration{time.Millisecond}})
+	runner.Handle(Rule{}, Do)
+
+	resp, err := runner.Execute(WithCallName(newRequest(t), "getUserProfile"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.StatusCode != 200 || calls != 2 {
+		t.Fatalf("expected the call policy to retry, got %d after %d calls", resp.StatusCode, calls)
+	}
+
+	calls = 0
+	resp, err = runner.Execute(WithCallName(newRequest(t), "listUsers"))
+	if err != nil || resp.StatusCode != 503 || calls != 1 {
+		t.Fatalf("expected unknown call to fall back to rules, got %v after %d calls", err, calls)
+	}
+}
+
+func Test_Runner_WithTemporaryPolicy(t *testing.T) {
+	var calls int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		calls++
+		if calls%3 != 0 {
+			return &http.Response{Request: r, StatusCode: 503}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	runner := &Runner{Client: client}
+	runner.SetCallPolicy("getUserProfile", Policy{Statuses: []int{200}, Intervals: []time.Duration{time.Millisecond}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runner.WithTemporaryPolicy(ctx, Policy{Intervals: []time.Duration{time.Millisecond, time.Millisecond}})
+	resp, err := runner.Execute(WithCallName(newRequest(t), "getUserProfile"))
+	if err != nil || resp.StatusCode != 200 || calls != 3 {
+		t.Fatalf("expected the override to retry twice, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	resp, err = runner.Execute(newRequest(t))
+	if err != nil || calls != 1 {
+		t.Fatalf("expected request without call policy executed with the override, got %v after %d calls", err, calls)
+	}
+
+	cancel()
+	calls = 0
+	_, err = runner.Execute(WithCallName(newRequest(t), "getUserProfile"))
+	if err == nil || calls != 2 {
+		t.Fatalf("expected the call policy reverted, got %v after %d calls", err, calls)
+	}
+}
+
+type sinkFunc func(callName string, dumps []Dump)
+
+func (f sinkFunc) Store(callName string, dumps []Dump) {
+	f(callName, dumps)
+}
+
+func Test_Runner_SetSampler(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200, Header: http.Header{}}, nil
+	})
+	samples := map[string][]Dump{}
+	sampler := NewSampler(sinkFunc(func(callName string, dumps []Dump) {
+		samples[callName] = append(samples[callName], dumps...)
+	}), 0, "X-Api-Key")
+	sampler.SetRate("getUserProfile", 1)
+	runner := &Runner{Client: client}
+	runner.SetSampler(sampler)
+
+	req := newRequest(t)
+	req.Header.Set("X-Api-Key", "secret")
+	for i := 0; i < 3; i++ {
+		runner.Execute(WithCallName(req, "getUserProfile"))
+		runner.Execute(WithCallName(req, "listUsers"))
+	}
+
+	if len(samples) != 1 || len(samples["getUserProfile"]) != 3 {
+		t.Fatalf("expected 3 samples of getUserProfile only, got %v", samples)
+	}
+	if dump := string(samples["getUserProfile"][0].Request); strings.Contains(dump, "secret") {
+		t.Fatalf("expected the key to be redacted, got %s", dump)
+	}
+}
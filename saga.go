@@ -0,0 +1,126 @@
+package reqstrategy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Step is a single step of the Saga or Waterfall
+type Step struct {
+	Request *http.Request
+	// Name identifies the step in errors, see StepError
+	Name string
+	// Timeout limits the step duration including reading the response body, not limited if zero
+	Timeout time.Duration
+	// Validate is applied to the step response in addition to the request validators, see WithValidator
+	Validate func(*http.Response) error
+	// Compensate builds the request undoing the step from its response, e.g. DELETE of the created resource.
+	// Step is not compensated if Compensate is <nil>
+	Compensate func(resp *http.Response) (*http.Request, error)
+}
+
+// send sends the step request with Do applying the step timeout and validator
+func (s Step) send(client *http.Client, r *http.Request) (*http.Response, error) {
+	if s.Validate != nil {
+		r = WithValidator(r, s.Validate)
+	}
+	if s.Timeout <= 0 {
+		return Do(client, r)
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), s.Timeout)
+	resp, err := Do(client, r.WithContext(ctx))
+	cancelOnClose(resp, cancel)
+	return resp, err
+}
+
+// SagaError is returned by Saga when a step failed, it reports compensation outcomes as well
+type SagaError struct {
+	// Step is the index of the failed step
+	Step int
+	Err  error
+	// Compensations holds compensation errors by step index, <nil> for the steps compensated successfully
+	Compensations map[int]error
+}
+
+func (e *SagaError) Error() string {
+	var failed []string
+	for step := e.Step - 1; step >= 0; step-- {
+		if err, ok := e.Compensations[step]; ok && err != nil {
+			failed = append(failed, fmt.Sprintf("step %d: %s", step, err))
+		}
+	}
+	msg := fmt.Sprintf("saga step %d failed: %s", e.Step, e.Err)
+	if len(failed) != 0 {
+		msg += fmt.Sprintf(", %d of %d compensations failed: %s", len(failed), len(e.Compensations), strings.Join(failed, "; "))
+	}
+	return msg
+}
+
+func (e *SagaError) Unwrap() error {
+	return e.Err
+}
+
+// Compensated reports whether every succeeded step was undone
+func (e *SagaError) Compensated() bool {
+	for _, err := range e.Compensations {
+		if err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Saga sends step requests one by one with Do applying step timeouts and validators. If a step fails,
+// compensations of the previously succeeded steps are sent in reverse order retrying with provided
+// intervals, see Retry, and *SagaError is returned. Responses of all steps are returned on success,
+// otherwise step response bodies are closed
+func Saga(client *http.Client, intervals []time.Duration, steps ...Step) ([]*http.Response, error) {
+	responses := make([]*http.Response, 0, len(steps))
+	for i, step := range steps {
+		resp, err := step.send(client, withStrategy(step.Request, "Saga"))
+		if err == nil {
+			responses = append(responses, resp)
+			continue
+		}
+		if resp != nil && resp.Body != nil {
+			resp.Body.Close()
+		}
+
+		sagaErr := &SagaError{Step: i, Err: err, Compensations: make(map[int]error)}
+		for j := len(responses) - 1; j >= 0; j-- {
+			if steps[j].Compensate != nil {
+				sagaErr.Compensations[j] = compensate(client, intervals, steps[j], responses[j])
+			}
+			if responses[j].Body != nil {
+				responses[j].Body.Close()
+			}
+		}
+		return nil, sagaErr
+	}
+	return responses, nil
+}
+
+func compensate(client *http.Client, intervals []time.Duration, step Step, resp *http.Response) error {
+	r, err := step.Compensate(resp)
+	if err != nil {
+		return err
+	}
+	r, err = rewindable(withStrategy(r, "Saga"), MaxBufferedBody)
+	if err != nil {
+		return err
+	}
+	resp, err = retry(r, intervals, func(attempt int) (*http.Response, error) {
+		req, err := rewind(r, attempt)
+		if err != nil {
+			return nil, err
+		}
+		return Do(client, withAttempt(req, attempt))
+	})
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	return err
+}
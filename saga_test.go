@@ -0,0 +1,67 @@
+package reqstrategy
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Saga(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	var refundAttempts int
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		switch {
+		case r.URL.Path == "/shipment":
+			return &http.Response{StatusCode: 500, Request: r}, nil
+		case r.Method == "DELETE" && r.URL.Path == "/payment":
+			refundAttempts++
+			if refundAttempts == 1 {
+				return nil, errors.New("connection reset")
+			}
+		}
+		return &http.Response{StatusCode: 200, Request: r}, nil
+	})
+	step := func(path string) Step {
+		return Step{
+			Request: WithStatusRequired(newRequest(t, path), 200),
+			Compensate: func(resp *http.Response) (*http.Request, error) {
+				return http.NewRequest("DELETE", resp.Request.URL.String(), nil)
+			},
+		}
+	}
+
+	_, err := Saga(client, []time.Duration{time.Millisecond}, step("order"), step("payment"), step("shipment"))
+
+	sagaErr, ok := err.(*SagaError)
+	if !ok {
+		t.Fatalf("expected *SagaError, got %v", err)
+	}
+	if sagaErr.Step != 2 || !sagaErr.Compensated() || len(sagaErr.Compensations) != 2 {
+		t.Fatalf("expected step 2 failure with 2 successful compensations, got %s", sagaErr)
+	}
+	expected := []string{"GET /order", "GET /payment", "GET /shipment", "DELETE /payment", "DELETE /payment", "DELETE /order"}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("expected calls %v, got %v", expected, calls)
+		}
+	}
+}
+
+func Test_Saga_success(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: 200, Request: r}, nil
+	})
+	responses, err := Saga(client, nil, Step{Request: newRequest(t, "a")}, Step{Request: newRequest(t, "b")})
+	if err != nil || len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d: %v", len(responses), err)
+	}
+}
@@ -0,0 +1,67 @@
+package reqstrategy
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SampleSink stores calls recorded by Sampler, e.g. to build corpora for contract tests
+type SampleSink interface {
+	// Store receives dumps of every attempt of the sampled call. It is called synchronously once
+	// the call is complete, so it should not block
+	Store(callName string, dumps []Dump)
+}
+
+// Sampler records a fraction of calls made with Runner, see Runner.SetSampler. Headers are redacted
+// the same way as in DumpBundle. It is safe for concurrent use
+type Sampler struct {
+	sink   SampleSink
+	rate   float64
+	redact []string
+
+	mu    sync.Mutex
+	rates map[string]float64
+	rand  *rand.Rand
+}
+
+// NewSampler creates Sampler recording the rate fraction of calls, e.g. 0.01 for 1%, redacting listed
+// headers in addition to the ones DumpBundle always redacts
+func NewSampler(sink SampleSink, rate float64, redact ...string) *Sampler {
+	return &Sampler{
+		sink:   sink,
+		rate:   rate,
+		redact: redact,
+		rates:  make(map[string]float64),
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetRate overrides the sampling rate for calls with the name, see WithCallName
+func (s *Sampler) SetRate(callName string, rate float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rates[callName] = rate
+}
+
+// sample decides whether to record the call, returning the request with dumps attached and the function
+// passing them to the sink once the call is complete
+func (s *Sampler) sample(r *http.Request) (*http.Request, func()) {
+	name, _ := r.Context().Value(CallNameKey).(string)
+	s.mu.Lock()
+	rate, ok := s.rates[name]
+	if !ok {
+		rate = s.rate
+	}
+	sampled := s.rand.Float64() < rate
+	s.mu.Unlock()
+	if !sampled {
+		return r, func() {}
+	}
+
+	bundle := NewDumpBundle(s.redact...)
+	return WithDumps(r, bundle), func() {
+		s.sink.Store(name, bundle.Dumps())
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !reqstrategy_det
+// +build !reqstrategy_det
+
+package reqstrategy
+
+// spawn runs the function concurrently
+func spawn(f func()) {
+	go f()
+}
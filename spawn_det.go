@@ -0,0 +1,10 @@
+//go:build reqstrategy_det
+// +build reqstrategy_det
+
+package reqstrategy
+
+// spawn runs the function in the caller's goroutine, so requests are sent one by one in the order
+// they were passed to the strategy, making the outcome reproducible
+func spawn(f func()) {
+	f()
+}
@@ -0,0 +1,31 @@
+//go:build reqstrategy_det
+// +build reqstrategy_det
+
+package reqstrategy
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_Race_deterministic(t *testing.T) {
+	var paths []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		paths = append(paths, r.URL.Path)
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	for i := 0; i < 10; i++ {
+		paths = nil
+		response, err := Race(client, newRequest(t, "a"), newRequest(t, "b"), newRequest(t, "c"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if response.Request.URL.Path != "/a" {
+			t.Fatalf(`expected "/a" to win, got "%s"`, response.Request.URL.Path)
+		}
+		if len(paths) != 3 || paths[0] != "/a" || paths[1] != "/b" || paths[2] != "/c" {
+			t.Fatalf("expected requests to be sent in order, got %v", paths)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+//go:build !reqstrategy_det
+// +build !reqstrategy_det
+
+package reqstrategy
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_WithStagger_stopped(t *testing.T) {
+	var calls int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	var requests []*http.Request
+	for i := 0; i < 3; i++ {
+		requests = append(requests, WithStagger(newRequest(t), time.Hour, 0))
+	}
+	resp, err := Race(client, requests...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resp.Body.Close()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected only the first request sent, got %d", n)
+	}
+}
+
+func Test_FirstN_completionOrder(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/a" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	responses, err := FirstN(client, 2, newRequest(t, "a"), newRequest(t, "b"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if responses[0].Request.URL.Path != "/b" || responses[1].Request.URL.Path != "/a" {
+		t.Fatalf("expected responses in completion order")
+	}
+}
+
+func Test_SomeWithin(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case "/a":
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		case "/c":
+			select {
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			case <-time.After(time.Second):
+			}
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	start := time.Now()
+	responses, err := SomeWithin(client, 20*time.Millisecond,
+		WithStatusRequired(newRequest(t, "a"), 200),
+		WithStatusRequired(newRequest(t, "b"), 200),
+		WithStatusRequired(newRequest(t, "c"), 200),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if responses[0] != nil || responses[1] == nil || responses[2] != nil {
+		t.Fatalf("expected response of b only, got %v", responses)
+	}
+
+	_, err = SomeWithin(client, 20*time.Millisecond,
+		WithStatusRequired(newRequest(t, "a"), 200),
+		WithStatusRequired(newRequest(t, "c"), 200),
+	)
+	errs, ok := err.(Errors)
+	if !ok || errs[0] == nil || errs[1] != context.DeadlineExceeded {
+		t.Fatalf("expected errors of both requests, got %v", err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("expected requests in flight to be cancelled at the deadline")
+	}
+}
+
+func Test_WithDelay_Race(t *testing.T) {
+	var secondary int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case "/secondary":
+			atomic.AddInt32(&secondary, 1)
+		case "/failing":
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			case <-time.After(time.Second):
+			}
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	race := func(primary string, delay time.Duration) (*http.Response, time.Duration) {
+		start := time.Now()
+		resp, err := Race(client,
+			WithStatusRequired(newRequest(t, primary), 200),
+			WithDelay(WithStatusRequired(newRequest(t, "secondary"), 200), delay),
+		)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return resp, time.Since(start)
+	}
+
+	if resp, _ := race("fast", 100*time.Millisecond); resp.Request.URL.Path != "/fast" || atomic.LoadInt32(&secondary) != 0 {
+		t.Fatalf("expected primary to win without sending the secondary, got %s", resp.Request.URL.Path)
+	}
+	if resp, took := race("slow", 20*time.Millisecond); resp.Request.URL.Path != "/secondary" || took > 500*time.Millisecond {
+		t.Fatalf("expected delayed secondary to win, got %s in %s", resp.Request.URL.Path, took)
+	}
+	if resp, took := race("failing", time.Second); resp.Request.URL.Path != "/secondary" || took > 500*time.Millisecond {
+		t.Fatalf("expected secondary to be sent once primary failed, got %s in %s", resp.Request.URL.Path, took)
+	}
+}
+
+// contextBody streams the content while the request context is alive
+type contextBody struct {
+	ctx  context.Context
+	data []byte
+}
+
+func (b *contextBody) Read(p []byte) (int, error) {
+	if err := b.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if len(b.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, b.data)
+	b.data = b.data[n:]
+	return n, nil
+}
+
+func (b *contextBody) Close() error {
+	return nil
+}
+
+func Test_RaceStream(t *testing.T) {
+	winner := make(chan context.Context, 1)
+	loser := make(chan error, 1)
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			loser <- r.Context().Err()
+			return nil, r.Context().Err()
+		}
+		winner <- r.Context()
+		return &http.Response{Request: r, StatusCode: 200, Body: &contextBody{r.Context(), []byte("streamed")}}, nil
+	})
+
+	resp, err := RaceStream(client, WithStatusRequired(newRequest(t, "slow"), 200), WithStatusRequired(newRequest(t, "fast"), 200))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := <-loser; err != context.Canceled {
+		t.Fatalf("expected loser cancelled, got %v", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || string(body) != "streamed" {
+		t.Fatalf(`expected "streamed" body, got "%s": %v`, body, err)
+	}
+	resp.Body.Close()
+	select {
+	case <-(<-winner).Done():
+	case <-time.After(time.Second):
+		t.Fatalf("expected winner context cancelled once body is closed")
+	}
+}
+
+func Test_WithRetryPolicy_Race(t *testing.T) {
+	retried := make(chan struct{}, 1)
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/flaky" {
+			return nil, context.DeadlineExceeded
+		}
+		time.Sleep(20 * time.Millisecond)
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	flaky := WithHook(WithRetryPolicy(newRequest(t, "flaky"), RetryPolicy{InitialInterval: time.Hour}), func(a Attempt) {
+		retried <- struct{}{}
+	})
+
+	start := time.Now()
+	resp, err := Race(client, flaky, newRequest(t, "stable"))
+	if err != nil || resp.Request.URL.Path != "/stable" {
+		t.Fatalf(`expected "/stable" to win while the flaky leg waits to retry, got %v`, err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("expected the race not to wait for the retry, took %s", time.Since(start))
+	}
+	<-retried
+}
+
+func Test_Attempt_canceledByStrategy(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return nil, r.Context().Err()
+		}
+		return &http.Response{Request: r, StatusCode: 200, Body: http.NoBody}, nil
+	})
+	attempts := make(chan Attempt, 2)
+	hook := func(a Attempt) { attempts <- a }
+
+	resp, err := Race(client, WithHook(newRequest(t, "fast"), hook), WithHook(newRequest(t, "slow"), hook))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resp.Body.Close()
+	for i := 0; i < 2; i++ {
+		a := <-attempts
+		slow := a.Request.URL.Path == "/slow"
+		if a.Canceled != slow {
+			t.Fatalf("expected %s attempt canceled %t, got %t", a.Request.URL.Path, slow, a.Canceled)
+		}
+		if slow && a.Err == nil {
+			t.Fatalf("expected cancelled attempt to keep the error")
+		}
+	}
+}
+
+func Test_SomeOrdered_byCompletion(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	results, err := SomeOrdered(client, ByCompletion, newRequest(t, "slow"), newRequest(t, "fast"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if results[0].Index != 1 || results[1].Index != 0 {
+		t.Fatalf("expected the fast response first, got %d, %d", results[0].Index, results[1].Index)
+	}
+}
+
+func Test_RaceKeep_slowest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/win" {
+			time.Sleep(30 * time.Millisecond)
+		}
+		w.(http.Flusher).Flush()
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+	get := func(path string) *http.Request {
+		r, _ := http.NewRequest("GET", server.URL+path, nil)
+		return r
+	}
+
+	type loser struct {
+		index int
+		body  string
+		err   error
+	}
+	losers := make(chan loser, 3)
+	resp, err := RaceKeep(server.Client(), 1, func(i int, resp *http.Response, err error) {
+		if err != nil {
+			losers <- loser{i, "", err}
+			return
+		}
+		body, err := readBody(resp)
+		losers <- loser{i, body, err}
+	}, get("/win"), WithDelay(get("/delayed"), 10*time.Millisecond), get("/slow"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resp.Body.Close()
+	l := <-losers
+	if l.index != 2 || l.err != nil || l.body != "/slow" {
+		t.Fatalf("expected the slowest request kept and its body readable, got #%d %q: %v", l.index, l.body, l.err)
+	}
+	select {
+	case l := <-losers:
+		t.Fatalf("expected only one loser reported, got %d", l.index)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func Test_Policy_Execute_hedges(t *testing.T) {
+	var mu sync.Mutex
+	var hosts []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		hosts = append(hosts, r.URL.Host)
+		mu.Unlock()
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	policy := Policy{
+		Statuses:   []int{200},
+		Endpoints:  []string{"http://a", "http://b", "http://c"},
+		Hedges:     1,
+		HedgeDelay: time.Second,
+	}
+	if _, err := policy.Execute(client, newRequest(t)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(hosts) != 1 || hosts[0] != "a" {
+		t.Fatalf("expected only the first endpoint to be sent to, got %v", hosts)
+	}
+}
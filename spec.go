@@ -0,0 +1,132 @@
+package reqstrategy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Spec is a declarative description of the request and the way to execute it, meant to be loaded
+// from JSON files by CLI tools, e.g.
+//
+//	{
+//	  "method": "POST",
+//	  "url": "https://api.example.com/orders",
+//	  "headers": {"Content-Type": "application/json"},
+//	  "body_file": "order.json",
+//	  "status": [200, 201],
+//	  "retry": ["1s", "2s", "4s"],
+//	  "timeout": "10s"
+//	}
+type Spec struct {
+	// Name identifies the spec in reports
+	Name string `json:"name,omitempty"`
+	// After lists names of the specs which have to pass before this one is run, see RunSuite
+	After []string `json:"after,omitempty"`
+	// Method defaults to GET
+	Method string `json:"method,omitempty"`
+	URL    string `json:"url"`
+	// Headers are added to the request
+	Headers map[string]string `json:"headers,omitempty"`
+	// Body is sent as is, BodyFile is read instead when set
+	Body     string `json:"body,omitempty"`
+	BodyFile string `json:"body_file,omitempty"`
+	// Status lists acceptable response statuses, see WithStatusRequired
+	Status []int `json:"status,omitempty"`
+	// Contains lists substrings the response body is required to have
+	Contains []string `json:"contains,omitempty"`
+	// Retry lists intervals between retries in time.ParseDuration format, see Retry
+	Retry []string `json:"retry,omitempty"`
+	// Timeout caps the total time of the call including all retries, in time.ParseDuration format
+	Timeout string `json:"timeout,omitempty"`
+	// Race lists base URLs the request is raced across, see Policy.Endpoints
+	Race []string `json:"race,omitempty"`
+	// IdempotencyKey makes requests sent with IdempotencyKeyHeader, see Policy.IdempotencyKey
+	IdempotencyKey bool `json:"idempotency_key,omitempty"`
+}
+
+// Request builds the request described by the spec
+func (s Spec) Request() (*http.Request, error) {
+	body := []byte(s.Body)
+	if s.BodyFile != "" {
+		var err error
+		if body, err = ioutil.ReadFile(s.BodyFile); err != nil {
+			return nil, err
+		}
+	}
+	method := s.Method
+	if method == "" {
+		method = "GET"
+	}
+	r, err := http.NewRequest(method, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		r.Body, r.GetBody = http.NoBody, nil
+	}
+	for name, value := range s.Headers {
+		r.Header.Set(name, value)
+	}
+	if len(s.Contains) != 0 {
+		r = WithValidator(r, func(r *http.Response) error {
+			body, err := bufferBody(r)
+			if err != nil {
+				return fmt.Errorf("%s %s: failed to read response body: %s", r.Request.Method, r.Request.URL, err)
+			}
+			for _, substring := range s.Contains {
+				if !bytes.Contains(body, []byte(substring)) {
+					return fmt.Errorf("%s %s: expected response body to contain %q", r.Request.Method, r.Request.URL, substring)
+				}
+			}
+			return nil
+		})
+	}
+	return r, nil
+}
+
+// Policy returns the policy the spec is executed with
+func (s Spec) Policy() (Policy, error) {
+	p := Policy{
+		Statuses:       s.Status,
+		Endpoints:      s.Race,
+		IdempotencyKey: s.IdempotencyKey,
+	}
+	if s.Method != "" {
+		p.Methods = []string{s.Method}
+	}
+	for _, interval := range s.Retry {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return Policy{}, fmt.Errorf("invalid retry interval: %s", err)
+		}
+		p.Intervals = append(p.Intervals, d)
+	}
+	if s.Timeout != "" {
+		d, err := time.ParseDuration(s.Timeout)
+		if err != nil {
+			return Policy{}, fmt.Errorf("invalid timeout: %s", err)
+		}
+		p.MaxElapsed = d
+	}
+	return p, nil
+}
+
+// RunSpec executes the request described by the spec returning the response and the error of the
+// strategy along with the report of what was done. Report is <nil> if the spec is invalid
+func RunSpec(client *http.Client, s Spec) (*http.Response, *Report, error) {
+	r, err := s.Request()
+	if err != nil {
+		return nil, nil, err
+	}
+	p, err := s.Policy()
+	if err != nil {
+		return nil, nil, err
+	}
+	report := NewReport()
+	resp, err := p.Execute(client, WithReport(r, report))
+	report.Finish(resp, err)
+	return resp, report, err
+}
@@ -0,0 +1,57 @@
+package reqstrategy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_RunSpec(t *testing.T) {
+	var bodies []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if len(bodies) == 1 {
+			return &http.Response{StatusCode: 503, Request: r, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+		}
+		return &http.Response{StatusCode: 201, Request: r, Body: ioutil.NopCloser(strings.NewReader(`{"id": 1}`))}, nil
+	})
+
+	var spec Spec
+	err := json.Unmarshal([]byte(`{
+		"method": "POST",
+		"url": "http://localhost/orders",
+		"headers": {"Content-Type": "application/json"},
+		"body": "{}",
+		"status": [201],
+		"contains": ["\"id\""],
+		"retry": ["1ms"]
+	}`), &spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	resp, report, err := RunSpec(client, spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.StatusCode != 201 || report.Err() != nil || len(report.Attempts()) != 2 {
+		t.Fatalf("unexpected outcome: status %d, %d attempts, %v", resp.StatusCode, len(report.Attempts()), report.Err())
+	}
+	if len(bodies) != 2 || bodies[0] != "{}" || bodies[1] != "{}" {
+		t.Fatalf("expected body sent with every attempt, got %q", bodies)
+	}
+}
+
+func Test_RunSpec_invalid(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		t.Fatal("unexpected request")
+		return nil, nil
+	})
+	_, report, err := RunSpec(client, Spec{URL: "http://localhost/", Retry: []string{"soon"}})
+	if err == nil || report != nil {
+		t.Fatalf("expected invalid spec error without report, got %v", err)
+	}
+}
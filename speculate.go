@@ -0,0 +1,94 @@
+package reqstrategy
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Speculate sends the request and, if it did not succeed within delay, sends the second attempt of it
+// without waiting for the first one to fail, returning whichever is validated first. Unlike Hedge the
+// second request is the retry of the same call: it carries the attempt metadata (see WithHook, WithCallID)
+// and counts as the retry. Only idempotent requests not marked with WithNoRetry or WithNoHedge are
+// speculated, i.e. GET, HEAD, OPTIONS, TRACE, PUT and DELETE or requests with IdempotencyKeyHeader,
+// others are sent once. The attempt which lost is cancelled through the context. Error of the last
+// attempt is returned if both failed. Request body is sent again the same way as by Retry
+func Speculate(client *http.Client, request *http.Request, delay time.Duration) (*http.Response, error) {
+	request = withStrategy(request, "Speculate")
+	if !idempotent(request) || noRetry(request) || noHedge(request) {
+		return Do(client, withAttempt(request, 1))
+	}
+	request, err := rewindable(request, MaxBufferedBody)
+	if err != nil {
+		return nil, err
+	}
+	if err := planRequests([]*http.Request{request}, 2); err != nil {
+		return nil, err
+	}
+
+	results := make(chan result, 2)
+	cancels := make([]context.CancelFunc, 2)
+	launch := func(n int) {
+		r, err := rewind(request, n)
+		if err != nil {
+			results <- result{n, nil, err}
+			return
+		}
+		ctx, cancel := context.WithCancel(r.Context())
+		cancels[n-1] = cancel
+		// attempts are driven by time, so they are sent concurrently even in deterministic mode
+		go func() {
+			resp, err := Do(client, withAttempt(r.WithContext(ctx), n))
+			results <- result{n, resp, err}
+		}()
+	}
+
+	launch(1)
+	timer := clockOf(request).After(delay)
+	launched, received := 1, 0
+	for {
+		select {
+		case res := <-results:
+			received++
+			if res.err == nil {
+				for n, cancel := range cancels {
+					if n+1 != res.order && cancel != nil {
+						cancel()
+					}
+				}
+				cancelOnClose(res.response, cancels[res.order-1])
+				if received < launched {
+					go func() {
+						closeBody((<-results).response)
+					}()
+				}
+				return res.response, nil
+			}
+			closeBody(res.response)
+			if cancels[res.order-1] != nil {
+				cancels[res.order-1]()
+			}
+			if received == 2 {
+				return nil, res.err
+			}
+			if received == launched {
+				timer = nil
+				launch(2)
+				launched++
+			}
+		case <-timer:
+			timer = nil
+			launch(2)
+			launched++
+		}
+	}
+}
+
+// idempotent reports whether the request may be safely sent more than once
+func idempotent(r *http.Request) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return r.Header.Get(IdempotencyKeyHeader) != ""
+}
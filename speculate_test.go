@@ -0,0 +1,74 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Speculate(t *testing.T) {
+	var calls int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			case <-time.After(time.Second):
+			}
+		}
+		return &http.Response{Request: r, StatusCode: 200, Body: http.NoBody}, nil
+	})
+
+	var mu sync.Mutex
+	attempts := map[string]error{}
+	done := make(chan struct{}, 2)
+	r := WithHook(WithCallID(newRequest(t), "abc"), func(a Attempt) {
+		mu.Lock()
+		attempts[a.CallID] = a.Err
+		mu.Unlock()
+		done <- struct{}{}
+	})
+	resp, err := Speculate(client, r, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if id := resp.Request.Header.Get(CallIDHeader); id != "abc.2" {
+		t.Fatalf("expected the second attempt to win, got %s", id)
+	}
+	resp.Body.Close()
+	<-done
+	<-done
+	mu.Lock()
+	defer mu.Unlock()
+	if err, ok := attempts["abc.1"]; !ok || err == nil {
+		t.Fatalf("expected the first attempt to be cancelled, got %v", attempts)
+	}
+}
+
+func Test_Speculate_notIdempotent(t *testing.T) {
+	var calls int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	r, _ := http.NewRequest("POST", "http://localhost/orders", nil)
+	if _, err := Speculate(client, r, time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("expected POST to be sent once, got %d", n)
+	}
+
+	r.Header.Set(IdempotencyKeyHeader, "key")
+	atomic.StoreInt32(&calls, 0)
+	if _, err := Speculate(client, r, time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("expected POST with idempotency key to be speculated, got %d", n)
+	}
+}
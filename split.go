@@ -0,0 +1,186 @@
+package reqstrategy
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Arm is a variant of the traffic split
+type Arm struct {
+	Name string
+	// Weight is the relative share of traffic the arm gets
+	Weight float64
+	// Endpoint is the base URL requests of the arm are sent to, only scheme and host are taken from it
+	Endpoint string
+}
+
+// ArmStats are outcomes of the requests sent to the arm
+type ArmStats struct {
+	Requests int
+	Failures int
+	// Latency is the total time requests took
+	Latency time.Duration
+}
+
+// StopRule collapses the split to the control arm once another arm degrades measurably, see Split.SetStopRule
+type StopRule struct {
+	// Control is the name of the arm all traffic goes to once the split is stopped
+	Control string
+	// MinRequests is the number of requests both the arm and the control need before they are compared,
+	// values below 1 mean 1
+	MinRequests int
+	// MaxErrorRateDelta is the largest acceptable excess of the arm error rate over the control one, e.g. 0.05
+	MaxErrorRateDelta float64
+	// OnStop is called once the split is stopped with the name of the degraded arm and stats at that moment
+	OnStop func(arm string, stats map[string]ArmStats)
+}
+
+// Split distributes traffic between arms by weight, e.g. for canary releases or A/B experiments.
+// Assignment is sticky: requests with the same unit key, e.g. user ID, always go to the same arm.
+// It is safe for concurrent use
+type Split struct {
+	arms  []Arm
+	total float64
+
+	mu      sync.Mutex
+	stats   map[string]*ArmStats
+	rule    *StopRule
+	stopped bool
+}
+
+// NewSplit creates the Split between arms, arm names must be unique
+func NewSplit(arms ...Arm) (*Split, error) {
+	s := &Split{arms: append([]Arm(nil), arms...), stats: make(map[string]*ArmStats)}
+	for _, arm := range arms {
+		if arm.Weight < 0 {
+			return nil, errors.New("arm weight must not be negative")
+		}
+		if _, ok := s.stats[arm.Name]; ok {
+			return nil, fmt.Errorf("duplicate arm %q", arm.Name)
+		}
+		s.total += arm.Weight
+		s.stats[arm.Name] = &ArmStats{}
+	}
+	if s.total == 0 {
+		return nil, errors.New("split has no arms with weight")
+	}
+	return s, nil
+}
+
+// SetStopRule sets the rule checked as outcomes are recorded, rule of the stopped split has no effect
+func (s *Split) SetStopRule(rule StopRule) error {
+	for _, arm := range s.arms {
+		if arm.Name == rule.Control {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.rule = &rule
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown control arm %q", rule.Control)
+}
+
+// Stopped reports whether the split was collapsed to the control arm by the stop rule
+func (s *Split) Stopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+// Assign returns the arm for the unit key, random arm is picked for the empty key.
+// Control arm is returned for all keys once the split is stopped
+func (s *Split) Assign(unit string) Arm {
+	s.mu.Lock()
+	stopped, rule := s.stopped, s.rule
+	s.mu.Unlock()
+	if stopped {
+		for _, arm := range s.arms {
+			if arm.Name == rule.Control {
+				return arm
+			}
+		}
+	}
+
+	point := rand.Float64()
+	if unit != "" {
+		h := fnv.New64a()
+		h.Write([]byte(unit))
+		point = float64(h.Sum64()%1000000) / 1000000
+	}
+	point *= s.total
+	for _, arm := range s.arms {
+		if point < arm.Weight {
+			return arm
+		}
+		point -= arm.Weight
+	}
+	for i := len(s.arms) - 1; i >= 0; i-- {
+		if s.arms[i].Weight > 0 {
+			return s.arms[i]
+		}
+	}
+	return s.arms[len(s.arms)-1]
+}
+
+// Do sends the request to the arm assigned to the unit key with Do, recording the outcome
+func (s *Split) Do(client *http.Client, r *http.Request, unit string) (*http.Response, error) {
+	arm := s.Assign(unit)
+	r, err := retarget(withStrategy(r, "Split"), arm.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := Do(client, r)
+	s.record(arm.Name, time.Since(start), err)
+	return resp, err
+}
+
+// Stats returns outcomes of every arm by name
+func (s *Split) Stats() map[string]ArmStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make(map[string]ArmStats, len(s.stats))
+	for name, st := range s.stats {
+		stats[name] = *st
+	}
+	return stats
+}
+
+func (s *Split) record(arm string, latency time.Duration, err error) {
+	s.mu.Lock()
+	st := s.stats[arm]
+	st.Requests++
+	st.Latency += latency
+	if err != nil {
+		st.Failures++
+	}
+	rule := s.rule
+	if rule == nil || s.stopped || arm == rule.Control {
+		s.mu.Unlock()
+		return
+	}
+	min := rule.MinRequests
+	if min < 1 {
+		min = 1
+	}
+	control, ok := s.stats[rule.Control]
+	if !ok || st.Requests < min || control.Requests < min {
+		s.mu.Unlock()
+		return
+	}
+	delta := float64(st.Failures)/float64(st.Requests) - float64(control.Failures)/float64(control.Requests)
+	if delta <= rule.MaxErrorRateDelta {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	s.mu.Unlock()
+	if rule.OnStop != nil {
+		rule.OnStop(arm, s.Stats())
+	}
+}
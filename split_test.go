@@ -0,0 +1,127 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func Test_Split(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "b" {
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	split, err := NewSplit(
+		Arm{Name: "control", Weight: 3, Endpoint: "http://a"},
+		Arm{Name: "treatment", Weight: 1, Endpoint: "http://b"},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		user := "user-" + strconv.Itoa(i%100)
+		resp, _ := split.Do(client, WithStatusRequired(newRequest(t), 200), user)
+		if arm := split.Assign(user); resp.Request.URL.Host != arm.Endpoint[len("http://"):] {
+			t.Fatalf("expected %s to stick to %s, got %s", user, arm.Name, resp.Request.URL.Host)
+		}
+	}
+
+	stats := split.Stats()
+	control, treatment := stats["control"], stats["treatment"]
+	if control.Requests+treatment.Requests != 1000 || control.Failures != 0 || treatment.Failures != treatment.Requests {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if share := float64(treatment.Requests) / 1000; share < 0.1 || share > 0.4 {
+		t.Fatalf("expected treatment to get about 25%% of traffic, got %.2f", share)
+	}
+}
+
+func Test_Split_SetStopRule(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "b" {
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	split, _ := NewSplit(
+		Arm{Name: "control", Weight: 1, Endpoint: "http://a"},
+		Arm{Name: "treatment", Weight: 1, Endpoint: "http://b"},
+	)
+	var stopped []string
+	err := split.SetStopRule(StopRule{
+		Control:           "control",
+		MinRequests:       10,
+		MaxErrorRateDelta: 0.1,
+		OnStop: func(arm string, stats map[string]ArmStats) {
+			stopped = append(stopped, arm)
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		split.Do(client, WithStatusRequired(newRequest(t), 200), "user-"+strconv.Itoa(i))
+	}
+	if !split.Stopped() || len(stopped) != 1 || stopped[0] != "treatment" {
+		t.Fatalf("expected the split to be stopped once, got %v", stopped)
+	}
+	if failures := split.Stats()["treatment"].Failures; failures < 10 || failures > 30 {
+		t.Fatalf("expected treatment to stop getting traffic after the minimum sample, got %d failures", failures)
+	}
+	if arm := split.Assign("user-1"); arm.Name != "control" {
+		t.Fatalf("expected all traffic to go to control, got %s", arm.Name)
+	}
+
+	if err := split.SetStopRule(StopRule{Control: "unknown"}); err == nil {
+		t.Fatal("expected unknown control arm error")
+	}
+}
+
+func Test_Split_SetStopRule_zeroValue(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	split, _ := NewSplit(
+		Arm{Name: "control", Weight: 1, Endpoint: "http://a"},
+		Arm{Name: "treatment", Weight: 1, Endpoint: "http://b"},
+	)
+	var stopped []string
+	split.SetStopRule(StopRule{Control: "control", OnStop: func(arm string, stats map[string]ArmStats) {
+		stopped = append(stopped, arm)
+	}})
+
+	for i := 0; i < 100; i++ {
+		split.Do(client, newRequest(t), "user-"+strconv.Itoa(i))
+	}
+	if split.Stopped() || len(stopped) != 0 {
+		t.Fatalf("expected the healthy split not to be stopped, got %v", stopped)
+	}
+}
+
+func Test_Split_SetStopRule_unknownControl(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	split, _ := NewSplit(
+		Arm{Name: "control", Weight: 1, Endpoint: "http://a"},
+		Arm{Name: "treatment", Weight: 1, Endpoint: "http://b"},
+	)
+	if err := split.SetStopRule(StopRule{Control: "unknown"}); err == nil {
+		t.Fatal("expected unknown control arm error")
+	}
+
+	for i := 0; i < 10; i++ {
+		split.Do(client, newRequest(t), "user-"+strconv.Itoa(i))
+	}
+	if split.Stopped() {
+		t.Fatal("expected the split without the rule not to be stopped")
+	}
+
+	if _, err := NewSplit(Arm{Name: "control", Weight: 1}, Arm{Name: "control", Weight: 1}); err == nil {
+		t.Fatal("expected duplicate arm error")
+	}
+}
@@ -0,0 +1,28 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Spread clones the request for every host, e.g. to Race the same call against multiple regions. Hosts are base
+// URLs like "https://eu.example.com" or bare hosts keeping the request scheme, only the scheme and the host are
+// taken from them. Clones share the request context and get their own bodies with GetBody, the request body
+// without GetBody is read into memory first. Clones which could not be made fail with the error when sent
+func Spread(request *http.Request, hosts ...string) []*http.Request {
+	r, bodyErr := rewindable(request, -1)
+	clones := make([]*http.Request, len(hosts))
+	for i, host := range hosts {
+		if !strings.Contains(host, "://") {
+			host = request.URL.Scheme + "://" + host
+		}
+		err := bodyErr
+		if err == nil {
+			clones[i], err = retarget(r, host)
+		}
+		if err != nil {
+			clones[i] = withValue(request, keyInvalid, err)
+		}
+	}
+	return clones
+}
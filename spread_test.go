@@ -0,0 +1,47 @@
+package reqstrategy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_Spread(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r.URL.String()+" "+string(body))
+		return &http.Response{Request: r, StatusCode: 200, Body: http.NoBody}, nil
+	})
+
+	req, _ := http.NewRequest("POST", "http://localhost/orders?id=1", ioutil.NopCloser(strings.NewReader("data")))
+	requests := Spread(req, "https://eu.example.com", "us.example.com:8080")
+	if _, err := All(client, requests...); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sort.Strings(received)
+	expected := []string{"http://us.example.com:8080/orders?id=1 data", "https://eu.example.com/orders?id=1 data"}
+	if strings.Join(received, ", ") != strings.Join(expected, ", ") {
+		t.Fatalf("expected %v, got %v", expected, received)
+	}
+}
+
+func Test_Spread_invalid(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	requests := Spread(newRequest(t), "https://eu.example.com", "http://%zz")
+	if _, err := Do(client, requests[0]); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := Do(client, requests[1]); err == nil {
+		t.Fatalf("expected invalid host to fail")
+	}
+}
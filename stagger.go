@@ -0,0 +1,69 @@
+package reqstrategy
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+type stagger struct {
+	interval time.Duration
+	jitter   time.Duration
+}
+
+// WithStagger makes strategies sending requests simultaneously, like All or Race, ramp them up gradually
+// to avoid the thundering herd: request number i (counting from 0) is sent i*interval after the strategy
+// started plus the random delay up to jitter. Requests not sent by the time the result is determined are
+// not sent at all
+func WithStagger(r *http.Request, interval, jitter time.Duration) *http.Request {
+	return withValue(r, keyStagger, stagger{interval, jitter})
+}
+
+// WithDelay makes strategies sending requests simultaneously, like All or Race, send the request d after
+// the strategy started, e.g. to prefer the primary region adding the secondary one only if the primary did not
+// respond in 100ms. Race sends delayed requests right away once any request failed, for the fast failover.
+// Requests not sent by the time the result is determined are not sent at all. It adds up with WithStagger
+func WithDelay(r *http.Request, d time.Duration) *http.Request {
+	return withValue(r, keyDelay, d)
+}
+
+// staggered waits until the request is due to be sent counting from start, see WithStagger and WithDelay.
+// It returns false if stop was closed first, closing hurry makes the request due right away
+func staggered(r *http.Request, order int, start time.Time, stop, hurry <-chan struct{}) bool {
+	delay := plannedDelay(r, order)
+	if s, ok := r.Context().Value(keyStagger).(stagger); ok && s.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(s.jitter)))
+	}
+	clock := clockOf(r)
+	if delay = start.Add(delay).Sub(clock.Now()); delay <= 0 {
+		return true
+	}
+	select {
+	case <-clock.After(delay):
+		return true
+	case <-stop:
+		return false
+	case <-hurry:
+		return true
+	case <-r.Context().Done():
+		return true
+	}
+}
+
+// plannedDelay returns how long after the strategy started the request is due to be sent, not counting the jitter
+func plannedDelay(r *http.Request, order int) time.Duration {
+	delay, _ := r.Context().Value(keyDelay).(time.Duration)
+	if s, ok := r.Context().Value(keyStagger).(stagger); ok {
+		delay += time.Duration(order) * s.interval
+	}
+	return delay
+}
+
+// startTimes returns the current time of every request's clock, the reference point for staggered
+func startTimes(requests []*http.Request) []time.Time {
+	starts := make([]time.Time, len(requests))
+	for i, r := range requests {
+		starts[i] = clockOf(r).Now()
+	}
+	return starts
+}
@@ -0,0 +1,34 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_WithStagger_All(t *testing.T) {
+	var mu sync.Mutex
+	var sent []time.Time
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		sent = append(sent, time.Now())
+		mu.Unlock()
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	var requests []*http.Request
+	for i := 0; i < 4; i++ {
+		requests = append(requests, WithStagger(newRequest(t), 20*time.Millisecond, 0))
+	}
+	start := time.Now()
+	if _, err := All(client, requests...); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(sent) != 4 {
+		t.Fatalf("expected 4 requests sent, got %d", len(sent))
+	}
+	if last := sent[len(sent)-1].Sub(start); last < 60*time.Millisecond {
+		t.Fatalf("expected the last request sent after 60ms, sent after %s", last)
+	}
+}
@@ -0,0 +1,176 @@
+package reqstrategy
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// skewWeight is how much every new Date header sample moves the skew estimate
+const skewWeight = 0.2
+
+// Stats collects metrics of the requests made with WithStats option. It is safe for concurrent use
+// and is meant to be shared across requests and strategies
+type Stats struct {
+	// Buckets are upper bounds of latency histogram buckets, DefaultBuckets are used if empty
+	Buckets []time.Duration
+
+	mu        sync.Mutex
+	skew      map[string]time.Duration
+	hosts     map[string]*Traffic
+	tags      map[string]*Traffic
+	latencies map[string]*Histogram
+}
+
+// Traffic is the amount of body bytes transferred
+type Traffic struct {
+	Sent     int64
+	Received int64
+}
+
+// WithStats makes Do record request metrics into provided Stats
+func WithStats(r *http.Request, s *Stats) *http.Request {
+	return withValue(r, keyStats, s)
+}
+
+// Skew returns estimated difference between the host's clock and local time based on response Date
+// headers, positive value means the server clock is ahead. Zero is returned when nothing was observed
+func (s *Stats) Skew(host string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.skew[host]
+}
+
+// HostTraffic returns the amount of bytes exchanged with the host
+func (s *Stats) HostTraffic(host string) Traffic {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.hosts[host]; ok {
+		return *t
+	}
+	return Traffic{}
+}
+
+// TagTraffic returns the amount of bytes exchanged by requests marked with the tag, see WithTag
+func (s *Stats) TagTraffic(tag string) Traffic {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.tags[tag]; ok {
+		return *t
+	}
+	return Traffic{}
+}
+
+// HostLatency returns the histogram of time it took the host to respond with headers,
+// it is updated as requests complete. <nil> is returned when nothing was observed
+func (s *Stats) HostLatency(host string) *Histogram {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latencies[host]
+}
+
+// observe records the response received for a request sent at start. Response body
+// is replaced to account the bytes read by the caller
+func (s *Stats) observe(r *http.Request, resp *http.Response, start, end time.Time) {
+	if resp == nil {
+		return
+	}
+	tag, _ := r.Context().Value(keyTag).(string)
+	if resp.Body != nil {
+		resp.Body = &countingBody{resp.Body, func(n int) { s.count(r.URL.Host, tag, 0, int64(n)) }}
+	}
+	s.observeLatency(r.URL.Host, end.Sub(start))
+	s.observeDate(r, resp, start, end)
+}
+
+// observeBody returns a copy of the request counting its body as sent while the transport writes
+// it, so failed attempts and chunked uploads are accounted for as well
+func (s *Stats) observeBody(r *http.Request) *http.Request {
+	if r.Body == nil || r.Body == http.NoBody {
+		return r
+	}
+	tag, _ := r.Context().Value(keyTag).(string)
+	count := func(n int) { s.count(r.URL.Host, tag, int64(n), 0) }
+	counted := r.WithContext(r.Context())
+	counted.Body = &countingBody{r.Body, count}
+	if r.GetBody != nil {
+		counted.GetBody = func() (io.ReadCloser, error) {
+			body, err := r.GetBody()
+			if err != nil || body == http.NoBody {
+				return body, err
+			}
+			return &countingBody{body, count}, nil
+		}
+	}
+	return counted
+}
+
+func (s *Stats) observeLatency(host string, d time.Duration) {
+	s.mu.Lock()
+	h, ok := s.latencies[host]
+	if !ok {
+		if s.latencies == nil {
+			s.latencies = make(map[string]*Histogram)
+		}
+		h = NewHistogram(s.Buckets...)
+		s.latencies[host] = h
+	}
+	s.mu.Unlock()
+	h.Observe(d)
+}
+
+func (s *Stats) observeDate(r *http.Request, resp *http.Response, start, end time.Time) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+	// Date has a second precision, compare it with the middle of round trip
+	sample := date.Sub(start.Add(end.Sub(start) / 2))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.skew == nil {
+		s.skew = make(map[string]time.Duration)
+	}
+	if skew, ok := s.skew[r.URL.Host]; ok {
+		sample = skew + time.Duration(skewWeight*float64(sample-skew))
+	}
+	s.skew[r.URL.Host] = sample
+}
+
+func (s *Stats) count(host, tag string, sent, received int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hosts == nil {
+		s.hosts = make(map[string]*Traffic)
+		s.tags = make(map[string]*Traffic)
+	}
+	for _, t := range []*Traffic{traffic(s.hosts, host), traffic(s.tags, tag)} {
+		t.Sent += sent
+		t.Received += received
+	}
+}
+
+func traffic(m map[string]*Traffic, k string) *Traffic {
+	t, ok := m[k]
+	if !ok {
+		t = &Traffic{}
+		m[k] = t
+	}
+	return t
+}
+
+// countingBody reports the number of bytes on every read
+type countingBody struct {
+	io.ReadCloser
+	count func(n int)
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.count(n)
+	}
+	return n, err
+}
@@ -0,0 +1,130 @@
+package reqstrategy
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Stats_Skew(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+		return &http.Response{Request: r, StatusCode: 200, Header: http.Header{"Date": {date}}}, nil
+	})
+
+	stats := &Stats{}
+	if _, err := Do(client, WithStats(newRequest(t), stats)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	skew := stats.Skew("localhost")
+	if skew < 59*time.Minute || skew > 61*time.Minute {
+		t.Fatalf("expected skew about 1h, got %s", skew)
+	}
+	if skew := stats.Skew("example.com"); skew != 0 {
+		t.Fatalf("expected no skew for unknown host, got %s", skew)
+	}
+}
+
+func Test_Stats_Traffic(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		ioutil.ReadAll(r.Body)
+		return &http.Response{Request: r, StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("pong"))}, nil
+	})
+
+	stats := &Stats{}
+	req, _ := http.NewRequest("POST", "http://localhost/", strings.NewReader("ping!"))
+	resp, err := Do(client, WithStats(WithTag(req, "ping"), stats))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	want := Traffic{Sent: 5, Received: 4}
+	if got := stats.HostTraffic("localhost"); got != want {
+		t.Fatalf("expected host traffic %+v, got %+v", want, got)
+	}
+	if got := stats.TagTraffic("ping"); got != want {
+		t.Fatalf("expected tag traffic %+v, got %+v", want, got)
+	}
+}
+
+func Test_Stats_TrafficSentOnFailure(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		ioutil.ReadAll(r.Body)
+		return nil, errors.New("connection reset")
+	})
+
+	stats := &Stats{}
+	req, _ := http.NewRequest("POST", "http://localhost/", ioutil.NopCloser(strings.NewReader("chunked")))
+	if req.ContentLength != 0 {
+		t.Fatalf("expected unknown content length, got %d", req.ContentLength)
+	}
+	if _, err := Do(client, WithStats(req, stats)); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	want := Traffic{Sent: 7}
+	if got := stats.HostTraffic("localhost"); got != want {
+		t.Fatalf("expected host traffic %+v, got %+v", want, got)
+	}
+}
+
+func Test_Stats_HostLatency(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		time.Sleep(5 * time.Millisecond)
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	stats := &Stats{Buckets: LinearBuckets(time.Millisecond, time.Millisecond, 100)}
+	for i := 0; i < 3; i++ {
+		if _, err := Do(client, WithStats(newRequest(t), stats)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	h := stats.HostLatency("localhost")
+	if h == nil || h.Count() != 3 {
+		t.Fatalf("expected 3 latencies recorded, got %v", h)
+	}
+	if p99 := h.Quantile(0.99); p99 < 5*time.Millisecond {
+		t.Fatalf("expected P99 of at least 5ms, got %s", p99)
+	}
+	if stats.HostLatency("example.com") != nil {
+		t.Fatal("expected no latencies for unknown host")
+	}
+}
+
+func Test_Stats_WriteOpenMetrics(t *testing.T) {
+	stats := &Stats{Buckets: []time.Duration{10 * time.Millisecond, 100 * time.Millisecond}}
+	stats.count("localhost", "checkout", 10, 20)
+	stats.count("localhost", "", 0, 5)
+	stats.observeLatency("localhost", 5*time.Millisecond)
+	stats.observeLatency("localhost", 50*time.Millisecond)
+	stats.observeLatency("localhost", time.Second)
+
+	var b strings.Builder
+	if err := stats.WriteOpenMetrics(&b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, line := range []string{
+		`reqstrategy_host_sent_bytes_total{host="localhost"} 10`,
+		`reqstrategy_host_received_bytes_total{host="localhost"} 25`,
+		`reqstrategy_tag_received_bytes_total{tag="checkout"} 20`,
+		`reqstrategy_response_seconds_bucket{host="localhost",le="0.01"} 1`,
+		`reqstrategy_response_seconds_bucket{host="localhost",le="0.1"} 2`,
+		`reqstrategy_response_seconds_bucket{host="localhost",le="+Inf"} 3`,
+		`reqstrategy_response_seconds_sum{host="localhost"} 1.055`,
+		`reqstrategy_response_seconds_count{host="localhost"} 3`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Fatalf("expected %q in\n%s", line, b.String())
+		}
+	}
+	if strings.Contains(b.String(), `tag=""`) || !strings.HasSuffix(b.String(), "# EOF\n") {
+		t.Fatalf("unexpected output\n%s", b.String())
+	}
+}
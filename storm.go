@@ -0,0 +1,85 @@
+package reqstrategy
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// stormWeight is how much every finished window moves the baseline
+const stormWeight = 0.2
+
+// Storm describes the abnormal spike of retries to the host detected by StormDetector
+type Storm struct {
+	Host     string
+	Retries  int
+	Baseline float64
+}
+
+// StormDetector counts retries made by Retry and Queue per host in fixed time windows and reports a storm
+// once the count within the window exceeds the baseline (moving average of previous windows) by factor
+// and is at least min. Every host is reported at most once per window. It is safe for concurrent use
+type StormDetector struct {
+	window time.Duration
+	factor float64
+	min    int
+	alert  func(Storm)
+
+	mu    sync.Mutex
+	hosts map[string]*stormWindow
+}
+
+type stormWindow struct {
+	start    time.Time
+	retries  int
+	baseline float64
+	alerted  bool
+}
+
+// NewStormDetector creates StormDetector calling alert when the storm is detected, alert is called
+// synchronously by the retrying strategy so it should not block
+func NewStormDetector(window time.Duration, factor float64, min int, alert func(Storm)) *StormDetector {
+	return &StormDetector{window: window, factor: factor, min: min, alert: alert, hosts: make(map[string]*stormWindow)}
+}
+
+// WithStormDetector makes retries of the request counted by the StormDetector
+func WithStormDetector(r *http.Request, d *StormDetector) *http.Request {
+	return withValue(r, keyStorm, d)
+}
+
+func (d *StormDetector) retried(host string, now time.Time) {
+	d.mu.Lock()
+	w, ok := d.hosts[host]
+	if !ok {
+		w = &stormWindow{start: now}
+		d.hosts[host] = w
+	}
+	if passed := now.Sub(w.start) / d.window; passed > 0 {
+		// windows passed after the last one had no retries
+		w.baseline += stormWeight * (float64(w.retries) - w.baseline)
+		w.baseline *= math.Pow(1-stormWeight, float64(passed-1))
+		w.start = w.start.Add(passed * d.window)
+		w.retries = 0
+		w.alerted = false
+	}
+	w.retries++
+	storm := Storm{host, w.retries, w.baseline}
+	alert := !w.alerted && w.retries >= d.min && float64(w.retries) > d.factor*w.baseline
+	if alert {
+		w.alerted = true
+	}
+	d.mu.Unlock()
+
+	if alert {
+		d.alert(storm)
+	}
+}
+
+// observeRetry counts the request in its StormDetector if it is a retry
+func observeRetry(r *http.Request) {
+	attempt, _ := r.Context().Value(keyAttempt).(int)
+	if d, ok := r.Context().Value(keyStorm).(*StormDetector); ok && attempt > 1 {
+		d.retried(r.URL.Host, clockOf(r).Now())
+	}
+}
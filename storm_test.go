@@ -0,0 +1,31 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_StormDetector(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 503}, nil
+	})
+
+	var storms []Storm
+	detector := NewStormDetector(time.Hour, 2, 3, func(s Storm) {
+		storms = append(storms, s)
+	})
+	req := WithStormDetector(WithStatusRequired(newRequest(t), 200), detector)
+
+	Retry(client, req, time.Millisecond)
+	if len(storms) != 0 {
+		t.Fatalf("expected no storms, got %v", storms)
+	}
+	Retry(client, req, time.Millisecond, time.Millisecond, time.Millisecond)
+	if len(storms) != 1 {
+		t.Fatalf("expected 1 storm, got %v", storms)
+	}
+	if storms[0].Host != "localhost" || storms[0].Retries != 3 {
+		t.Fatalf("expected 3 retries to localhost, got %+v", storms[0])
+	}
+}
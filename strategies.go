@@ -23,18 +23,133 @@
 package reqstrategy
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sort"
 	"time"
 )
 
 // Do is not much different from calling client.Do(request) except it runs the
-// response validation. See WithValidator and WithSTatusRequired
+// response validation. See WithValidator and WithSTatusRequired. Request with the retry
+// policy is retried automatically, see WithRetryPolicy
 func Do(client *http.Client, request *http.Request) (*http.Response, error) {
-	resp, err := client.Do(request)
+	request = withStrategy(request, "Do")
+	if p, ok := request.Context().Value(keyRetryPolicy).(RetryPolicy); ok {
+		return p.do(client, request)
+	}
+	return send(client, request)
+}
+
+// send makes a single attempt of Do
+func send(client *http.Client, request *http.Request) (*http.Response, error) {
+	if expired(request, 0) {
+		return nil, ErrExpired
+	}
+	if err, _ := request.Context().Value(keyInvalid).(error); err != nil {
+		return nil, err
+	}
+	request, switched, err := switchTarget(request)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := sendTo(client, request)
+	switched(resp)
+	return resp, err
+}
+
+// sendTo makes the attempt of send once the request is retargeted by switchTarget
+func sendTo(client *http.Client, request *http.Request) (*http.Response, error) {
+	if err := allowed(request); err != nil {
+		return nil, err
+	}
+	request, err := deduplicate(request)
+	if err != nil {
+		return nil, err
+	}
+	if err := takeRequest(request); err != nil {
+		return nil, err
+	}
+	id, _ := request.Context().Value(keyCallID).(string)
+	if id != "" {
+		request = withHeader(request, CallIDHeader, id)
+	}
+	if err := approve(id, request); err != nil {
+		return nil, err
+	}
+	observeRetry(request)
+	request = withAnnotations(request)
+	release, err := acquire(request)
+	if err != nil {
+		return nil, err
+	}
+	request, dumped := startDump(id, request)
+	start := time.Now()
+	resp, err := roundTrip(client, request)
+	release()
+	dumped(resp, err)
+	trackLeak(id, request, resp)
+	notify(request, Attempt{
+		CallID:   id,
+		Request:  request,
+		Response: resp,
+		Err:      err,
+		Start:    start,
+		Duration: time.Since(start),
+		Canceled: err != nil && stoppedByStrategy(request),
+	})
+	return resp, err
+}
+
+// roundTrip sends the request and validates the response, it may take more than one physical
+// request when the preflight is required or the compression or the clock correction is negotiated
+func roundTrip(client *http.Client, request *http.Request) (*http.Response, error) {
+	if err := preflight(client, request); err != nil {
+		return nil, err
+	}
+	if c, _ := request.Context().Value(keyClockCorrection).(*clockCorrection); c != nil {
+		// the body is sent once more if the clock is corrected
+		var err error
+		if request, err = rewindable(request, MaxBufferedBody); err != nil {
+			return nil, err
+		}
+	}
+	request, plain, err := compress(request)
+	if err != nil {
+		return nil, err
+	}
+	if err := reserveBudgets(request); err != nil {
+		return nil, err
+	}
+	stats, _ := request.Context().Value(keyStats).(*Stats)
+	sent := request
+	if stats != nil {
+		sent = stats.observeBody(request)
+	}
+	start := time.Now()
+	watched, watch := startIdle(client, sent)
+	timed, finish := startTimeouts(watched)
+	resp, err := watch(finish(client.Do(timed)))
+	if err == nil {
+		startKeepAlive(client, request, resp)
+	}
+	spendBudgets(request, resp)
+	if stats != nil {
+		stats.observe(request, resp, start, time.Now())
+	}
 	if err != nil {
 		return resp, err
 	}
+	if plain != nil && resp.StatusCode == http.StatusUnsupportedMediaType {
+		plain.Context().Value(keyCompression).(*Compression).Disable(plain.URL.Host)
+		if resp.Body != nil {
+			resp.Body.Close()
+		}
+		return roundTrip(client, plain)
+	}
+	if c, _ := request.Context().Value(keyClockCorrection).(*clockCorrection); c != nil && c.match(resp) {
+		return c.retry(client, request, resp)
+	}
 	validators, _ := request.Context().Value(keyValidators).([]validator)
 	for _, validate := range validators {
 		if err := validate(resp); err != nil {
@@ -44,108 +159,592 @@ func Do(client *http.Client, request *http.Request) (*http.Response, error) {
 	return resp, nil
 }
 
-// Race runs requests simultaneously returning first successulf result or error if all failed.
-// Once result is determined all requests are cancelled through the context.
+// Errors is returned when all requests of the strategy failed, it holds their errors in the order of requests
+type Errors []error
+
+func (e Errors) Error() string {
+	return "all requests failed"
+}
+
+// Unwrap returns errors of the requests
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// Race runs requests simultaneously returning first successulf result or Errors if all failed.
+// Once result is determined all requests are cancelled through the context. Requests to hosts
+// in maintenance are not sent unless all of them are, see WithMaintenance, their Errors are
+// ErrInMaintenance. Requests may join the race later for the primary with fast failover semantics,
+// see WithDelay. Every request is retried on its own according to its retry policy before it
+// counts failed, see WithRetryPolicy
 func Race(client *http.Client, requests ...*http.Request) (*http.Response, error) {
-	results := make(chan result, len(requests))
-	stop := make(chan struct{})
-	defer close(stop)
+	return RaceVerbose(client, nil, requests...)
+}
 
-	for i, r := range requests {
-		go do(client, r, i, stop, results)
+// RaceVerbose is Race reporting errors of the requests which lost the race to onLoser, if it is not <nil>.
+// Requests still in flight when the winner is found are cancelled and reported once complete, in background.
+// Loser which succeeded too late is reported with <nil> error, its response body is closed. Requests not sent
+// because of maintenance are reported with ErrInMaintenance. Indexes and Errors follow the order of requests
+func RaceVerbose(client *http.Client, onLoser func(index int, err error), requests ...*http.Request) (*http.Response, error) {
+	all := withStrategies(requests, "Race")
+	requests, positions := avoidMaintenance(all)
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
 	}
+	stop, failover := make(chan struct{}), make(chan struct{})
+	defer close(stop)
+	results := runEach(client, requests, repeatStop(stop, len(requests)), failover)
 
-	var received int
-	for res := range results {
-		if res.err == nil {
+	errs := skippedErrors(len(all), positions)
+	for received := 1; received <= len(requests); received++ {
+		res := <-results
+		if res.err != nil {
+			if received == 1 {
+				close(failover)
+			}
+			errs[positions[res.order]] = res.err
+			continue
+		}
+		if onLoser == nil {
+			closeResults(results, len(requests)-received)
 			return res.response, nil
 		}
-		received++
-		if received == len(requests) {
-			break
+		for i, err := range errs {
+			if err != nil {
+				onLoser(i, err)
+			}
+		}
+		go reportLosers(results, len(requests)-received, func(i int, err error) {
+			onLoser(positions[i], err)
+		})
+		return res.response, nil
+	}
+
+	return nil, errs
+}
+
+// reportLosers passes the results of n requests which lost the race to onLoser
+func reportLosers(results <-chan result, n int, onLoser func(index int, err error)) {
+	for i := 0; i < n; i++ {
+		res := <-results
+		closeBody(res.response)
+		onLoser(res.order, res.err)
+	}
+}
+
+// RaceKeep is Race keeping up to keep slowest requests, which are still in flight when the winner is found, running
+// to completion instead of cancelling them, e.g. to warm caches with the losing responses. The slowest requests
+// are the ones in flight the longest, i.e. sent first, see WithDelay and WithStagger, requests sent at the same
+// time are kept in the order they are passed. The rest are cancelled as in Race. Results of kept requests are
+// passed to onLoser in background, the request is cancelled once onLoser returned and its response body closed,
+// closing the body is up to onLoser
+func RaceKeep(client *http.Client, keep int, onLoser func(index int, resp *http.Response, err error), requests ...*http.Request) (*http.Response, error) {
+	all := withStrategies(requests, "Race")
+	requests, positions := avoidMaintenance(all)
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
+	stops := make([]chan struct{}, len(requests))
+	receive := make([]<-chan struct{}, len(requests))
+	for i := range stops {
+		stops[i] = make(chan struct{})
+		receive[i] = stops[i]
+	}
+	failover := make(chan struct{})
+	results := runEach(client, requests, receive, failover)
+
+	done := make([]bool, len(requests))
+	errs := skippedErrors(len(all), positions)
+	for received := 1; received <= len(requests); received++ {
+		res := <-results
+		done[res.order] = true
+		if res.err != nil {
+			close(stops[res.order])
+			if received == 1 {
+				close(failover)
+			}
+			errs[positions[res.order]] = res.err
+			continue
+		}
+		var running []int
+		for i := range requests {
+			if !done[i] {
+				running = append(running, i)
+			}
+		}
+		sort.SliceStable(running, func(a, b int) bool {
+			return plannedDelay(requests[running[a]], running[a]) < plannedDelay(requests[running[b]], running[b])
+		})
+		kept := make(map[int]bool)
+		for j, i := range running {
+			if j < keep {
+				kept[i] = true
+			} else {
+				close(stops[i])
+			}
+		}
+		go func(n int) {
+			for ; n > 0; n-- {
+				res := <-results
+				if !kept[res.order] {
+					closeBody(res.response)
+					continue
+				}
+				onLoser(positions[res.order], res.response, res.err)
+				close(stops[res.order])
+			}
+		}(len(requests) - received)
+		return res.response, nil
+	}
+
+	return nil, errs
+}
+
+// RaceStream is Race for streaming endpoints, the first request which response headers pass validation wins
+// and its body is handed over right away while the rest are cancelled. Unlike Race, the winner keeps running
+// until its body is closed. Validators reading the body delay the decision, see WithValidator
+func RaceStream(client *http.Client, requests ...*http.Request) (*http.Response, error) {
+	all := withStrategies(requests, "Race")
+	requests, positions := avoidMaintenance(all)
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
+	stops := make([]chan struct{}, len(requests))
+	receive := make([]<-chan struct{}, len(requests))
+	for i := range stops {
+		stops[i] = make(chan struct{})
+		receive[i] = stops[i]
+	}
+	failover := make(chan struct{})
+	results := runEach(client, requests, receive, failover)
+
+	errs := skippedErrors(len(all), positions)
+	for received := 1; received <= len(requests); received++ {
+		res := <-results
+		if res.err != nil {
+			if received == 1 {
+				close(failover)
+			}
+			errs[positions[res.order]] = res.err
+			continue
 		}
+		for i := range stops {
+			if i != res.order {
+				close(stops[i])
+			}
+		}
+		go func(n int) {
+			for ; n > 0; n-- {
+				closeBody((<-results).response)
+			}
+		}(len(requests) - received)
+		return res.response, nil
 	}
 
-	return nil, fmt.Errorf("all requests failed")
+	return nil, errs
+}
+
+// Fallback tries requests one by one returning the first successful response, next request is only sent
+// after the previous one failed. Unlike Race it keeps expensive backup endpoints idle while the primary works.
+// Requests to hosts in maintenance are skipped unless all of them are, see WithMaintenance.
+// Error of the last request is returned if all of them failed, *CancelError if Fallback was cancelled
+func Fallback(client *http.Client, requests ...*http.Request) (*http.Response, error) {
+	requests, positions := avoidMaintenance(withStrategies(requests, "Fallback"))
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
+	err := fmt.Errorf("no requests provided")
+	for i, request := range requests {
+		if err := checkpoint(request, positions[i]); err != nil {
+			return nil, err
+		}
+		var resp *http.Response
+		resp, err = Do(client, withChildID(request, positions[i]+1))
+		if err == nil {
+			return resp, nil
+		}
+		closeBody(resp)
+		err = interrupted(request, positions[i], err)
+	}
+	return nil, err
+}
+
+// Mirror sends the primary request returning its response as soon as it is validated, while shadow copies
+// are sent in background without affecting the caller, e.g. to test a new backend on real traffic. Shadow
+// outcomes are not reported back, use WithHook to see them. Their response bodies are read and closed
+func Mirror(client *http.Client, primary *http.Request, shadows ...*http.Request) (*http.Response, error) {
+	for i, shadow := range shadows {
+		shadow := withChildID(withStrategy(shadow, "Mirror"), i+2)
+		go func() {
+			resp, _ := Do(client, shadow)
+			Discard(resp)
+		}()
+	}
+	return Do(client, withChildID(withStrategy(primary, "Mirror"), 1))
+}
+
+// Chain runs dependent requests one by one, every stage builds the request from the previous response
+// (<nil> for the first stage), e.g. to get a token and then make the call with it. Every request is validated
+// and the chain stops at the first failure, *CancelError is returned if the chain was cancelled. Previous response
+// body is closed once the next request is built, response of the last stage is returned
+func Chain(client *http.Client, stages ...func(prev *http.Response) (*http.Request, error)) (*http.Response, error) {
+	var resp *http.Response
+	for i, stage := range stages {
+		request, err := stage(resp)
+		closeBody(resp)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkpoint(request, i); err != nil {
+			return nil, err
+		}
+		resp, err = Do(client, withChildID(withStrategy(request, "Chain"), i+1))
+		if err != nil {
+			return resp, interrupted(request, i, err)
+		}
+	}
+	return resp, nil
 }
 
 // All runs requests simultaneously returning responses in same order or error if at least one request failed.
-// Once result is determined all requests are cancelled through the context.
+// Once result is determined all requests are cancelled through the context. Every request is retried on its own
+// according to its retry policy, so a transient failure does not fail the whole call, see WithRetryPolicy
 func All(client *http.Client, requests ...*http.Request) ([]*http.Response, error) {
-	results := make(chan result, len(requests))
+	requests = withStrategies(requests, "All")
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
 	stop := make(chan struct{})
 	defer close(stop)
+	return collectAll(run(client, requests, stop), len(requests))
+}
+
+// AllLimited is All sending at most limit requests at once, so it is usable with thousands of requests.
+// Requests are sent in the order they are passed, once result is determined the rest of them are not sent
+func AllLimited(client *http.Client, limit int, requests ...*http.Request) ([]*http.Response, error) {
+	requests = withStrategies(requests, "AllLimited")
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	return collectAll(runLimited(client, requests, limit, stop), len(requests))
+}
 
+// AllWithRetry is All re-attempting failed requests with provided intervals, see Retry, so the requests
+// which succeeded are not repeated, e.g. against rate limited APIs. Responses are returned in the order
+// of requests once all of them succeeded. Once any request failed its last attempt the rest are cancelled
+// through the context and its error is returned. Request bodies are sent again the same way as by Retry
+func AllWithRetry(client *http.Client, intervals []time.Duration, requests ...*http.Request) ([]*http.Response, error) {
+	requests, err := rewindables(withStrategies(requests, "AllWithRetry"))
+	if err != nil {
+		return nil, err
+	}
+	if err := planRequests(requests, len(intervals)+1); err != nil {
+		return nil, err
+	}
+	results := make(chan result, len(requests))
+	cancels := make([]context.CancelFunc, len(requests))
 	for i, r := range requests {
-		go do(client, r, i, stop, results)
+		i, r := i, withChildID(r, i+1)
+		ctx, cancel := context.WithCancel(r.Context())
+		r, cancels[i] = r.WithContext(ctx), cancel
+		spawn(func() {
+			resp, err := retry(r, intervals, func(attempt int) (*http.Response, error) {
+				req, err := rewind(r, attempt)
+				if err != nil {
+					return nil, err
+				}
+				return Do(client, withAttempt(req, attempt))
+			})
+			results <- result{i, resp, err}
+		})
+	}
+
+	responses := make([]*http.Response, len(requests))
+	for received := 1; received <= len(requests); received++ {
+		res := <-results
+		if res.err != nil {
+			closeBody(res.response)
+			for i, cancel := range cancels {
+				closeBody(responses[i])
+				cancel()
+			}
+			go reportLosers(results, len(requests)-received, func(int, error) {})
+			return nil, res.err
+		}
+		responses[res.order] = res.response
+	}
+	for i, resp := range responses {
+		cancelOnClose(resp, cancels[i])
 	}
+	return responses, nil
+}
 
+// collectAll receives n results returning responses in the order of requests or the first error
+func collectAll(results <-chan result, n int) ([]*http.Response, error) {
 	var received int
-	responses := make([]*http.Response, len(requests), len(requests))
-	for res := range results {
+	responses := make([]*http.Response, n, n)
+	for received < n {
+		res := <-results
 		if res.err != nil {
+			for _, resp := range responses {
+				closeBody(resp)
+			}
+			closeResults(results, n-received-1)
 			return nil, res.err
 		}
 		received++
 		responses[res.order] = res.response
-		if received == len(requests) {
-			break
-		}
 	}
 
 	return responses, nil
 }
 
 // Some runs requests simultaneously returning responses for successful requests and <nil> for failed ones.
-// Error is returned only if all requests failed.
+// Error is returned only if all requests failed, see Errors. Every request is retried on its own according
+// to its retry policy, see WithRetryPolicy
 func Some(client *http.Client, requests ...*http.Request) ([]*http.Response, error) {
-	results := make(chan result, len(requests))
+	requests = withStrategies(requests, "Some")
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
 	stop := make(chan struct{})
 	defer close(stop)
-
-	for i, r := range requests {
-		go do(client, r, i, stop, results)
-	}
+	results := run(client, requests, stop)
 
 	var received, successful int
+	errs := make(Errors, len(requests))
 	responses := make([]*http.Response, len(requests), len(requests))
 	for res := range results {
 		received++
 		if res.err == nil {
 			successful++
 			responses[res.order] = res.response
+		} else {
+			errs[res.order] = res.err
 		}
 		if received == len(requests) {
 			break
 		}
 	}
 	if successful == 0 {
-		return nil, fmt.Errorf("all requests failed")
+		return nil, errs
 	}
 
 	return responses, nil
 }
 
+// SomeWithin is Some with the soft deadline: responses of requests succeeded within d are returned,
+// <nil> for the rest, e.g. for the best effort aggregation rendering within the latency budget. Requests
+// still in flight by then are cancelled through the context. Error is returned only if no request succeeded,
+// see Errors, requests which did not complete in time fail with context.DeadlineExceeded
+func SomeWithin(client *http.Client, d time.Duration, requests ...*http.Request) ([]*http.Response, error) {
+	requests = withStrategies(requests, "SomeWithin")
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
+	var deadline <-chan time.Time
+	if len(requests) != 0 {
+		deadline = clockOf(requests[0]).After(d)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	results := run(client, requests, stop)
+
+	var successful int
+	errs := make(Errors, len(requests))
+	for i := range errs {
+		errs[i] = context.DeadlineExceeded
+	}
+	responses := make([]*http.Response, len(requests), len(requests))
+	collect := func(res result) {
+		if res.err == nil {
+			successful++
+			responses[res.order] = res.response
+		} else {
+			errs[res.order] = res.err
+		}
+	}
+wait:
+	for received := 0; received < len(requests); received++ {
+		select {
+		case res := <-results:
+			collect(res)
+		case <-deadline:
+			// results which are already there are in time too
+			for ; received < len(requests); received++ {
+				select {
+				case res := <-results:
+					collect(res)
+				default:
+					closeResults(results, len(requests)-received)
+					break wait
+				}
+			}
+		}
+	}
+	if successful == 0 {
+		return nil, errs
+	}
+
+	return responses, nil
+}
+
+// Quorum runs requests simultaneously returning as soon as k of them succeed, responses are returned in the
+// same order with <nil> for requests which failed or did not complete. Error is returned once k successes
+// become impossible, bodies of the responses received by then are closed. Once result is determined all requests
+// are cancelled through the context.
+func Quorum(client *http.Client, k int, requests ...*http.Request) ([]*http.Response, error) {
+	requests = withStrategies(requests, "Quorum")
+	if k > len(requests) {
+		return nil, fmt.Errorf("quorum of %d is not reachable with %d requests", k, len(requests))
+	}
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	results := run(client, requests, stop)
+
+	var successful, failed int
+	responses := make([]*http.Response, len(requests), len(requests))
+	for successful < k {
+		res := <-results
+		if res.err != nil {
+			failed++
+			if failed > len(requests)-k {
+				for _, resp := range responses {
+					closeBody(resp)
+				}
+				closeResults(results, len(requests)-successful-failed)
+				return nil, fmt.Errorf("quorum of %d not reached, %d of %d requests failed", k, failed, len(requests))
+			}
+			continue
+		}
+		successful++
+		responses[res.order] = res.response
+	}
+	closeResults(results, len(requests)-successful-failed)
+
+	return responses, nil
+}
+
+// FirstN runs requests simultaneously returning the first n successful responses in the order they arrived,
+// e.g. to sample the fastest replicas. It sits between Race (n = 1) and All (n = len(requests)). Error is
+// returned once n successes become impossible, bodies of the responses received by then are closed. Once result
+// is determined all requests are cancelled through the context.
+func FirstN(client *http.Client, n int, requests ...*http.Request) ([]*http.Response, error) {
+	requests = withStrategies(requests, "FirstN")
+	if n > len(requests) {
+		return nil, fmt.Errorf("%d responses are not reachable with %d requests", n, len(requests))
+	}
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	results := run(client, requests, stop)
+
+	var failed int
+	responses := make([]*http.Response, 0, n)
+	for len(responses) < n {
+		res := <-results
+		if res.err != nil {
+			failed++
+			if failed > len(requests)-n {
+				for _, resp := range responses {
+					closeBody(resp)
+				}
+				closeResults(results, len(requests)-len(responses)-failed)
+				return nil, fmt.Errorf("first %d responses not received, %d of %d requests failed", n, failed, len(requests))
+			}
+			continue
+		}
+		responses = append(responses, res.response)
+	}
+	closeResults(results, len(requests)-n-failed)
+
+	return responses, nil
+}
+
 // Retry re-attempts request with provided intervals. By manually providing intervals sequence you
 // can have different wait strategies like exponential back-off (time.Second, 2 * time.Second, 4 * time.Second)
 // or just multiple reties after same interval (time.Second, time.Second, time.Second). If Request had a context
-// with timeout cancelation then it will be applied to entire chain
+// with timeout cancelation then it will be applied to entire chain. Request marked with WithNoRetry is sent once.
+// Request body is sent again with GetBody, the body without GetBody is buffered up to MaxBufferedBody,
+// ErrBodyNotRewindable is returned if the larger one has to be sent again. Request with WithBackoff waits as
+// the backoff tells instead, attempts are limited with WithMaxAttempts and WithMaxElapsed
 func Retry(client *http.Client, request *http.Request, intervals ...time.Duration) (*http.Response, error) {
-	ctx := request.Context()
-	for true {
-		response, err := Do(client, request)
-		if err == nil {
-			return response, nil
+	request, err := rewindable(withStrategy(request, "Retry"), MaxBufferedBody)
+	if err != nil {
+		return nil, err
+	}
+	backoff, attempts := Backoff(Intervals(intervals...)), len(intervals)+1
+	if b, ok := request.Context().Value(keyBackoff).(Backoff); ok {
+		backoff, attempts = b, 1
+		if n, _ := request.Context().Value(keyMaxAttempts).(int); n > 0 {
+			attempts = n
 		}
-		if len(intervals) == 0 {
-			return response, err
+	}
+	if err := planRequests([]*http.Request{request}, plannedAttempts(request, attempts)); err != nil {
+		return nil, err
+	}
+	return retryBackoff(request, backoff, nil, limitsOf(request), func(attempt int) (*http.Response, error) {
+		r, err := rewind(request, attempt)
+		if err != nil {
+			return nil, err
 		}
-		select {
-		case <-time.After(intervals[0]):
-			intervals = intervals[1:]
-		case <-ctx.Done():
-			return nil, ctx.Err()
+		return Do(client, withAttempt(r, attempt))
+	})
+}
+
+// RetryBackoff is Retry waiting between attempts as the backoff tells, e.g. Exponential(time.Second, time.Minute).
+// Unlike intervals, backoff may not limit the number of attempts, so the request context deadline or
+// WithExpiry should limit the whole call
+func RetryBackoff(client *http.Client, request *http.Request, backoff Backoff) (*http.Response, error) {
+	request, err := rewindable(withStrategy(request, "Retry"), MaxBufferedBody)
+	if err != nil {
+		return nil, err
+	}
+	if err := planRequests([]*http.Request{request}, 1); err != nil {
+		return nil, err
+	}
+	return retryBackoff(request, backoff, nil, limitsOf(request), func(attempt int) (*http.Response, error) {
+		r, err := rewind(request, attempt)
+		if err != nil {
+			return nil, err
 		}
+		return Do(client, withAttempt(r, attempt))
+	})
+}
+
+// RetryFunc is RetryBackoff building the request for every attempt with build, so the attempt gets fresh
+// auth signatures, timestamps or re-opened bodies. The first request provides the context, clock, jitter and
+// retry classifier of the whole call, every request carries its own validators and other options.
+// Retries stop once build fails, its error is returned
+func RetryFunc(client *http.Client, build func(attempt int) (*http.Request, error), backoff Backoff) (*http.Response, error) {
+	first, err := build(1)
+	if err != nil {
+		return nil, err
+	}
+	first = withStrategy(first, "Retry")
+	if err := planRequests([]*http.Request{first}, 1); err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("retry loop failed")
+	var buildErr error
+	classify := classifierOf(first)
+	retryable := func(resp *http.Response, err error) bool {
+		return buildErr == nil && classify(resp, err)
+	}
+	return retryBackoff(first, backoff, retryable, limitsOf(first), func(attempt int) (*http.Response, error) {
+		r := first
+		if attempt > 1 {
+			if r, buildErr = build(attempt); buildErr != nil {
+				return nil, buildErr
+			}
+			r = withStrategy(r, "Retry")
+		}
+		return Do(client, withAttempt(r, attempt))
+	})
 }
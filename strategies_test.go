@@ -2,8 +2,12 @@ package reqstrategy
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -27,6 +31,30 @@ func newClient(roundTrip func(r *http.Request) (*http.Response, error)) *http.Cl
 	return &http.Client{Transport: transport(roundTrip)}
 }
 
+// newStreamingServer starts the server responding with the status from the "status" query parameter (200 by
+// default) and the request path as the body, the body is sent some time after headers like real streams are
+func newStreamingServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status, err := strconv.Atoi(r.URL.Query().Get("status")); err == nil {
+			w.WriteHeader(status)
+		}
+		w.(http.Flusher).Flush()
+		select {
+		case <-time.After(20 * time.Millisecond):
+		case <-r.Context().Done():
+			return
+		}
+		w.Write([]byte(r.URL.Path))
+	}))
+}
+
+// readBody reads and closes the response body
+func readBody(resp *http.Response) (string, error) {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	return string(body), err
+}
+
 func Test_Do(t *testing.T) {
 	client := newClient(func(r *http.Request) (*http.Response, error) {
 		return &http.Response{Request: r, StatusCode: 200}, nil
@@ -53,8 +81,9 @@ func Test_Do_error(t *testing.T) {
 	if err == nil {
 		t.Fatal("expected error, got <nil>")
 	}
-	if err.Error() != "Get http://localhost/: request failed" {
-		t.Fatalf(`expected error "Get http://localhost/: request failed", got "%s"`, err.Error())
+	want := `Get "http://localhost/": request failed`
+	if err.Error() != want {
+		t.Fatalf(`expected error "%s", got "%s"`, want, err.Error())
 	}
 }
 
@@ -149,6 +178,237 @@ func Test_Race_all_failed(t *testing.T) {
 	}
 }
 
+func Test_Race_errors(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 500}, nil
+	})
+
+	_, err := Race(client,
+		WithStatusRequired(newRequest(t, "a"), 200),
+		WithStatusRequired(newRequest(t, "b"), 200),
+	)
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("expected Errors of both requests, got %#v", err)
+	}
+	if !strings.Contains(errs[0].Error(), "/a") || !strings.Contains(errs[1].Error(), "/b") {
+		t.Fatalf("expected errors in the order of requests, got %v", []error(errs))
+	}
+}
+
+func Test_RaceVerbose(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case "/a":
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		case "/b":
+			time.Sleep(10 * time.Millisecond)
+			return &http.Response{Request: r, StatusCode: 200}, nil
+		}
+		select {
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		case <-time.After(time.Second):
+			return &http.Response{Request: r, StatusCode: 200}, nil
+		}
+	})
+
+	var mu sync.Mutex
+	losers := map[int]error{}
+	reported := make(chan struct{}, 2)
+	resp, err := RaceVerbose(client, func(i int, err error) {
+		mu.Lock()
+		losers[i] = err
+		mu.Unlock()
+		reported <- struct{}{}
+	},
+		WithStatusRequired(newRequest(t, "a"), 200),
+		WithStatusRequired(newRequest(t, "b"), 200),
+		WithStatusRequired(newRequest(t, "c"), 200),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.Request.URL.Path != "/b" {
+		t.Fatalf(`expected "/b" to win, got "%s"`, resp.Request.URL.Path)
+	}
+	<-reported
+	<-reported
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := losers[2]; len(losers) != 2 || losers[0] == nil || !ok {
+		t.Fatalf("expected both losers to be reported, got %v", losers)
+	}
+}
+
+func Test_RaceKeep(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/a" {
+			return &http.Response{Request: r, StatusCode: 200}, nil
+		}
+		select {
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		case <-time.After(20 * time.Millisecond):
+			return &http.Response{Request: r, StatusCode: 200}, nil
+		}
+	})
+
+	type loser struct {
+		index int
+		resp  *http.Response
+		err   error
+	}
+	losers := make(chan loser, 3)
+	resp, err := RaceKeep(client, 1, func(i int, resp *http.Response, err error) {
+		losers <- loser{i, resp, err}
+	}, newRequest(t, "a"), newRequest(t, "b"), newRequest(t, "c"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.Request.URL.Path != "/a" {
+		t.Fatalf(`expected "/a" to win, got "%s"`, resp.Request.URL.Path)
+	}
+	l := <-losers
+	if l.index != 1 || l.err != nil || l.resp.StatusCode != 200 {
+		t.Fatalf("expected kept request 1 to complete, got %d: %v", l.index, l.err)
+	}
+	select {
+	case l := <-losers:
+		t.Fatalf("expected only one loser reported, got %d", l.index)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func Test_Fallback(t *testing.T) {
+	var paths []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/a" {
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	response, err := Fallback(client,
+		WithStatusRequired(newRequest(t, "a"), 200),
+		WithStatusRequired(newRequest(t, "b"), 200),
+		WithStatusRequired(newRequest(t, "c"), 200),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if response.Request.URL.Path != "/b" {
+		t.Fatalf(`expected "/b" to succeed, got "%s"`, response.Request.URL.Path)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("expected requests to stop after the first success, got %v", paths)
+	}
+}
+
+func Test_Fallback_all_failed(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 500}, nil
+	})
+
+	response, err := Fallback(client,
+		WithStatusRequired(newRequest(t, "a"), 200),
+		WithStatusRequired(newRequest(t, "b"), 200),
+	)
+	if response != nil {
+		t.Fatalf("expected <nil> response")
+	}
+	if err == nil || !strings.Contains(err.Error(), "/b") {
+		t.Fatalf("expected error of the last request, got %v", err)
+	}
+}
+
+func Test_Mirror(t *testing.T) {
+	shadowed := make(chan string, 2)
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/primary" {
+			return &http.Response{Request: r, StatusCode: 200}, nil
+		}
+		time.Sleep(50 * time.Millisecond)
+		shadowed <- r.URL.Path
+		return &http.Response{Request: r, StatusCode: 500, Body: ioutil.NopCloser(strings.NewReader("oops"))}, nil
+	})
+
+	start := time.Now()
+	response, err := Mirror(client,
+		WithStatusRequired(newRequest(t, "primary"), 200),
+		WithStatusRequired(newRequest(t, "shadow1"), 200),
+		WithStatusRequired(newRequest(t, "shadow2"), 200),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if response.Request.URL.Path != "/primary" || time.Since(start) > 40*time.Millisecond {
+		t.Fatalf("expected primary response without waiting for shadows, got %s after %s", response.Request.URL.Path, time.Since(start))
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-shadowed:
+		case <-time.After(time.Second):
+			t.Fatal("expected shadows to be sent")
+		}
+	}
+}
+
+func Test_Chain(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/token" {
+			return &http.Response{Request: r, StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("secret"))}, nil
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			return &http.Response{Request: r, StatusCode: 401}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	response, err := Chain(client,
+		func(*http.Response) (*http.Request, error) {
+			return WithStatusRequired(newRequest(t, "token"), 200), nil
+		},
+		func(prev *http.Response) (*http.Request, error) {
+			token, err := ioutil.ReadAll(prev.Body)
+			if err != nil {
+				return nil, err
+			}
+			r := WithStatusRequired(newRequest(t, "profile"), 200)
+			r.Header.Set("Authorization", "Bearer "+string(token))
+			return r, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if response.Request.URL.Path != "/profile" {
+		t.Fatalf(`expected response of "/profile", got "%s"`, response.Request.URL.Path)
+	}
+}
+
+func Test_Chain_failed(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 500}, nil
+	})
+
+	var stages int
+	_, err := Chain(client,
+		func(*http.Response) (*http.Request, error) {
+			stages++
+			return WithStatusRequired(newRequest(t, "token"), 200), nil
+		},
+		func(*http.Response) (*http.Request, error) {
+			stages++
+			return newRequest(t, "profile"), nil
+		},
+	)
+	if err == nil || stages != 1 {
+		t.Fatalf("expected chain to stop at the first failure, got %d stages: %v", stages, err)
+	}
+}
+
 func Test_All(t *testing.T) {
 	client := newClient(func(r *http.Request) (*http.Response, error) {
 		switch r.URL.Path {
@@ -324,3 +584,250 @@ func Test_Retry_error(t *testing.T) {
 		t.Fatalf(`expected "%s" error, got "%s"`, want, err.Error())
 	}
 }
+
+func Test_Quorum(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case "/a":
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		case "/d":
+			select {
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			case <-time.After(time.Second):
+			}
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	responses, err := Quorum(client, 2,
+		WithStatusRequired(newRequest(t, "a"), 200),
+		WithStatusRequired(newRequest(t, "b"), 200),
+		WithStatusRequired(newRequest(t, "c"), 200),
+		WithStatusRequired(newRequest(t, "d"), 200),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if responses[0] != nil || responses[1] == nil || responses[2] == nil || responses[3] != nil {
+		t.Fatalf("expected responses of b and c only, got %v", responses)
+	}
+}
+
+func Test_Quorum_unreachable(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/c" {
+			return &http.Response{Request: r, StatusCode: 200}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 500}, nil
+	})
+
+	_, err := Quorum(client, 2,
+		WithStatusRequired(newRequest(t, "a"), 200),
+		WithStatusRequired(newRequest(t, "b"), 200),
+		WithStatusRequired(newRequest(t, "c"), 200),
+	)
+	if err == nil || !strings.Contains(err.Error(), "quorum of 2 not reached") {
+		t.Fatalf("expected quorum error, got %v", err)
+	}
+}
+
+func Test_Quorum_closesBodies(t *testing.T) {
+	var closed int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/ok" {
+			return &http.Response{Request: r, StatusCode: 200, Body: &trackedBody{strings.NewReader(""), &closed}}, nil
+		}
+		time.Sleep(10 * time.Millisecond)
+		return &http.Response{Request: r, StatusCode: 500, Body: http.NoBody}, nil
+	})
+	requests := func() []*http.Request {
+		var requests []*http.Request
+		for _, path := range []string{"ok", "fail", "fail"} {
+			requests = append(requests, WithStatusRequired(newRequest(t, path), 200))
+		}
+		return requests
+	}
+
+	if _, err := Quorum(client, 2, requests()...); err == nil {
+		t.Fatalf("expected quorum error")
+	}
+	if n := atomic.LoadInt32(&closed); n != 1 {
+		t.Fatalf("expected the successful response closed by Quorum, got %d closed", n)
+	}
+	if _, err := FirstN(client, 2, requests()...); err == nil {
+		t.Fatalf("expected FirstN error")
+	}
+	if n := atomic.LoadInt32(&closed); n != 2 {
+		t.Fatalf("expected the successful response closed by FirstN, got %d closed", n-1)
+	}
+}
+
+func Test_FirstN(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case "/a":
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		case "/d":
+			select {
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			case <-time.After(time.Second):
+			}
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	requests := func() []*http.Request {
+		var requests []*http.Request
+		for _, path := range []string{"a", "b", "c", "d"} {
+			requests = append(requests, WithStatusRequired(newRequest(t, path), 200))
+		}
+		return requests
+	}
+
+	responses, err := FirstN(client, 2, requests()...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	paths := map[string]bool{}
+	for _, resp := range responses {
+		paths[resp.Request.URL.Path] = true
+	}
+	if len(responses) != 2 || !paths["/b"] || !paths["/c"] {
+		t.Fatalf("expected responses of b and c, got %v", paths)
+	}
+
+	_, err = FirstN(client, 4, requests()...)
+	if err == nil || !strings.Contains(err.Error(), "first 4 responses not received") {
+		t.Fatalf("expected error, got %v", err)
+	}
+}
+
+func Test_AllWithRetry(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[r.URL.Path]++
+		if r.URL.Path == "/c" || r.URL.Path == "/a" && calls["/a"] == 1 {
+			return &http.Response{Request: r, StatusCode: 429}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	intervals := []time.Duration{time.Millisecond, time.Millisecond}
+
+	responses, err := AllWithRetry(client, intervals,
+		WithStatusRequired(newRequest(t, "a"), 200),
+		WithStatusRequired(newRequest(t, "b"), 200),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(responses) != 2 || responses[0].Request.URL.Path != "/a" || responses[1].Request.URL.Path != "/b" {
+		t.Fatalf("expected responses in order of requests, got %v", responses)
+	}
+	if calls["/a"] != 2 || calls["/b"] != 1 {
+		t.Fatalf("expected only failed request to be retried, got %v", calls)
+	}
+
+	_, err = AllWithRetry(client, intervals,
+		WithStatusRequired(newRequest(t, "b"), 200),
+		WithStatusRequired(newRequest(t, "c"), 200),
+	)
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("expected error of c, got %v", err)
+	}
+	if calls["/c"] != 3 || calls["/b"] != 2 {
+		t.Fatalf("expected c to be attempted 3 times, got %v", calls)
+	}
+}
+
+func Test_AllLimited(t *testing.T) {
+	var inflight, peak int32
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	var requests []*http.Request
+	for i := 0; i < 50; i++ {
+		requests = append(requests, newRequest(t, strconv.Itoa(i)))
+	}
+	responses, err := AllLimited(client, 4, requests...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, resp := range responses {
+		if resp.Request.URL.Path != "/"+strconv.Itoa(i) {
+			t.Fatalf("expected response #%d for %q, got %q", i, "/"+strconv.Itoa(i), resp.Request.URL.Path)
+		}
+	}
+	if peak > 4 {
+		t.Fatalf("expected at most 4 requests in flight, got %d", peak)
+	}
+}
+
+func Test_AllLimited_failed(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/3" {
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	var requests []*http.Request
+	for i := 0; i < 10; i++ {
+		requests = append(requests, WithStatusRequired(newRequest(t, strconv.Itoa(i)), 200))
+	}
+	responses, err := AllLimited(client, 2, requests...)
+	if responses != nil {
+		t.Fatalf("expected <nil> responses")
+	}
+	if err == nil || !strings.Contains(err.Error(), "/3") {
+		t.Fatalf("expected error of the failed request, got %v", err)
+	}
+}
+
+func Test_streamedBodies(t *testing.T) {
+	server := newStreamingServer()
+	defer server.Close()
+	get := func(path string) *http.Request {
+		r, _ := http.NewRequest("GET", server.URL+path, nil)
+		return WithStatusRequired(r, 200)
+	}
+
+	responses, err := All(server.Client(), get("/a"), get("/b"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, resp := range responses {
+		if body, err := readBody(resp); err != nil || body != []string{"/a", "/b"}[i] {
+			t.Fatalf("expected All response #%d readable, got %q, %v", i, body, err)
+		}
+	}
+
+	resp, err := Race(server.Client(), get("/a"), get("/a"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if body, err := readBody(resp); err != nil || body != "/a" {
+		t.Fatalf("expected Race winner readable, got %q, %v", body, err)
+	}
+
+	responses, err = Quorum(server.Client(), 1, get("/a"), get("/b?status=500"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if body, err := readBody(responses[0]); err != nil || body != "/a" {
+		t.Fatalf("expected Quorum response readable, got %q, %v", body, err)
+	}
+}
@@ -0,0 +1,111 @@
+package reqstrategy
+
+import (
+	"context"
+	"net/http"
+)
+
+// Result is the outcome of a single request made by Stream
+type Result struct {
+	// Index is the position of the request in the list passed to the strategy
+	Index    int
+	Response *http.Response
+	Err      error
+}
+
+// Order is the order results of simultaneous requests are delivered in
+type Order int
+
+const (
+	// ByInput delivers results in the order of requests
+	ByInput Order = iota
+	// ByCompletion delivers results as requests complete, the fastest first
+	ByCompletion
+)
+
+// Stream runs requests simultaneously emitting results as they complete, so large fan-outs can be
+// processed without holding all the responses in memory. Cancelling ctx cancels requests in flight,
+// their results are still emitted with the error. Channel is closed once all results are emitted,
+// the caller has to drain it
+func Stream(ctx context.Context, client *http.Client, requests ...*http.Request) <-chan Result {
+	return StreamOrdered(ctx, client, ByCompletion, requests...)
+}
+
+// StreamOrdered is Stream emitting results in the order. With ByInput results of requests completed
+// before the preceding ones are held until those are emitted
+func StreamOrdered(ctx context.Context, client *http.Client, order Order, requests ...*http.Request) <-chan Result {
+	requests = withStrategies(requests, "Stream")
+	out := make(chan Result)
+	go func() {
+		defer close(out)
+		if err := planRequests(requests, 1); err != nil {
+			for i := range requests {
+				out <- Result{i, nil, err}
+			}
+			return
+		}
+
+		stop := make(chan struct{})
+		finished := make(chan struct{})
+		defer close(finished)
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-finished:
+			}
+			close(stop)
+		}()
+
+		results := run(client, requests, stop)
+		held := make(map[int]Result)
+		next := 0
+		for range requests {
+			res := <-results
+			if order == ByCompletion {
+				out <- Result{res.order, res.response, res.err}
+				continue
+			}
+			held[res.order] = Result{res.order, res.response, res.err}
+			for r, ok := held[next]; ok; r, ok = held[next] {
+				delete(held, next)
+				out <- r
+				next++
+			}
+		}
+	}()
+	return out
+}
+
+// SomeOrdered is Some returning results of all requests in the order, each with the index of its request,
+// e.g. ByCompletion to process the fastest responses first. Failed requests have Err set, error is returned
+// only if all requests failed
+func SomeOrdered(client *http.Client, order Order, requests ...*http.Request) ([]Result, error) {
+	requests = withStrategies(requests, "Some")
+	if err := planRequests(requests, 1); err != nil {
+		return nil, err
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	results := run(client, requests, stop)
+
+	var successful int
+	errs := make(Errors, len(requests))
+	collected := make([]Result, len(requests))
+	for received := range requests {
+		res := <-results
+		if res.err == nil {
+			successful++
+		} else {
+			errs[res.order] = res.err
+		}
+		i := res.order
+		if order == ByCompletion {
+			i = received
+		}
+		collected[i] = Result{res.order, res.response, res.err}
+	}
+	if successful == 0 {
+		return nil, errs
+	}
+	return collected, nil
+}
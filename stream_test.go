@@ -0,0 +1,106 @@
+package reqstrategy
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_Stream(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/0" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		if r.URL.Path == "/2" {
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	var requests []*http.Request
+	for i := 0; i < 3; i++ {
+		requests = append(requests, WithStatusRequired(newRequest(t, strconv.Itoa(i)), 200))
+	}
+	var order []int
+	for res := range Stream(context.Background(), client, requests...) {
+		order = append(order, res.Index)
+		if (res.Err != nil) != (res.Index == 2) {
+			t.Fatalf("unexpected result #%d: %v", res.Index, res.Err)
+		}
+		if res.Err == nil && res.Response.Request.URL.Path != "/"+strconv.Itoa(res.Index) {
+			t.Fatalf("unexpected response for #%d: %s", res.Index, res.Response.Request.URL.Path)
+		}
+	}
+	if len(order) != 3 {
+		t.Fatalf("expected 3 results, got %v", order)
+	}
+}
+
+func Test_Stream_cancel(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		<-r.Context().Done()
+		return nil, r.Context().Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var failed int
+	for res := range Stream(ctx, client, newRequest(t, "a"), newRequest(t, "b")) {
+		if res.Err != nil {
+			failed++
+		}
+	}
+	if failed != 2 {
+		t.Fatalf("expected both requests to be cancelled, got %d failures", failed)
+	}
+}
+
+func Test_StreamOrdered_byInput(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/0" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	var order []int
+	requests := []*http.Request{newRequest(t, "0"), newRequest(t, "1"), newRequest(t, "2")}
+	for res := range StreamOrdered(context.Background(), client, ByInput, requests...) {
+		if res.Err != nil {
+			t.Fatalf("unexpected error: %s", res.Err)
+		}
+		order = append(order, res.Index)
+	}
+	if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+		t.Fatalf("expected results in the order of requests, got %v", order)
+	}
+}
+
+func Test_SomeOrdered(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/1" {
+			return &http.Response{Request: r, StatusCode: 500}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+
+	var requests []*http.Request
+	for i := 0; i < 3; i++ {
+		requests = append(requests, WithStatusRequired(newRequest(t, strconv.Itoa(i)), 200))
+	}
+	results, err := SomeOrdered(client, ByInput, requests...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, res := range results {
+		if res.Index != i || (res.Err != nil) != (i == 1) {
+			t.Fatalf("unexpected result #%d: %d %v", i, res.Index, res.Err)
+		}
+	}
+
+	if _, err := SomeOrdered(client, ByCompletion, requests[1]); err == nil {
+		t.Fatalf("expected error when all requests failed")
+	}
+}
@@ -0,0 +1,83 @@
+package reqstrategy
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+)
+
+// SpecResult is the outcome of a single spec run by RunSuite
+type SpecResult struct {
+	Name    string  `json:"name"`
+	Passed  bool    `json:"passed"`
+	Skipped bool    `json:"skipped,omitempty"`
+	Error   string  `json:"error,omitempty"`
+	Report  *Report `json:"report,omitempty"`
+}
+
+// RunSuite executes specs as a smoke-test suite running up to parallel specs at once, specs are run
+// after the ones listed in their After have passed and skipped if any of those failed. Response bodies
+// are read and closed. Results are returned in the order of specs, error is returned only if the suite
+// can not be run because of unknown, duplicate or cyclic dependencies
+func RunSuite(client *http.Client, specs []Spec, parallel int) ([]SpecResult, error) {
+	names := make(map[string]int, len(specs))
+	labels := make([]string, len(specs))
+	after := make([][]string, len(specs))
+	for i, s := range specs {
+		if s.Name != "" {
+			names[s.Name] = i
+		}
+		labels[i], after[i] = s.Name, s.After
+	}
+	order, err := planDependencies(labels, after)
+	if err != nil {
+		return nil, err
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]SpecResult, len(specs))
+	done := make([]chan struct{}, len(specs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	wg.Add(len(specs))
+	for _, i := range order {
+		i, s := i, specs[i]
+		spawn(func() {
+			defer wg.Done()
+			defer close(done[i])
+
+			results[i].Name = s.Name
+			for _, name := range s.After {
+				dep := names[name]
+				<-done[dep]
+				if !results[dep].Passed {
+					results[i].Skipped = true
+					results[i].Error = fmt.Sprintf("dependency %q did not pass", name)
+					return
+				}
+			}
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			resp, report, err := RunSpec(client, s)
+			if resp != nil && resp.Body != nil {
+				io.Copy(ioutil.Discard, resp.Body)
+				resp.Body.Close()
+			}
+			results[i].Report = report
+			results[i].Passed = err == nil
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		})
+	}
+	wg.Wait()
+	return results, nil
+}
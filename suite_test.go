@@ -0,0 +1,60 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func Test_RunSuite(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/broken" {
+			return &http.Response{StatusCode: 500, Request: r}, nil
+		}
+		return &http.Response{StatusCode: 200, Request: r}, nil
+	})
+
+	specs := []Spec{
+		{Name: "list", URL: "http://localhost/orders", Status: []int{200}, After: []string{"login"}},
+		{Name: "login", URL: "http://localhost/login", Status: []int{200}},
+		{Name: "broken", URL: "http://localhost/broken", Status: []int{200}},
+		{Name: "details", URL: "http://localhost/details", Status: []int{200}, After: []string{"broken", "list"}},
+	}
+	results, err := RunSuite(client, specs, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !results[0].Passed || !results[1].Passed {
+		t.Fatalf("expected list and login to pass, got %+v", results)
+	}
+	if results[2].Passed || results[2].Error == "" || results[2].Report == nil {
+		t.Fatalf("expected broken to fail with report, got %+v", results[2])
+	}
+	if !results[3].Skipped || results[3].Report != nil {
+		t.Fatalf("expected details to be skipped, got %+v", results[3])
+	}
+	for i, path := range paths {
+		if path == "/orders" && (i == 0 || !containsString(paths[:i], "/login")) {
+			t.Fatalf("expected /orders to be requested after /login, got %v", paths)
+		}
+	}
+	if len(paths) != 3 {
+		t.Fatalf("expected 3 requests, got %v", paths)
+	}
+}
+
+func Test_RunSuite_cycle(t *testing.T) {
+	specs := []Spec{
+		{Name: "a", URL: "http://localhost/a", After: []string{"b"}},
+		{Name: "b", URL: "http://localhost/b", After: []string{"a"}},
+	}
+	if _, err := RunSuite(http.DefaultClient, specs, 1); err == nil {
+		t.Fatal("expected dependency cycle error")
+	}
+}
@@ -0,0 +1,120 @@
+package reqstrategy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Switch selects the active group of endpoints for client-driven blue/green cutovers, e.g.
+//
+//	s, _ := NewSwitch("blue", map[string][]string{
+//	  "blue":  {"https://blue-1.internal", "https://blue-2.internal"},
+//	  "green": {"https://green-1.internal"},
+//	})
+//	resp, err := Retry(client, WithSwitch(req, s), time.Second)
+//	...
+//	s.SetActive("green")
+//	err = s.Drain(ctx, "blue")
+//
+// It is safe for concurrent use
+type Switch struct {
+	mu       sync.Mutex
+	groups   map[string][]string
+	active   string
+	inflight map[string]int
+}
+
+// NewSwitch creates the Switch with groups of base URLs, only scheme and host are taken from them
+func NewSwitch(active string, groups map[string][]string) (*Switch, error) {
+	s := &Switch{groups: make(map[string][]string), inflight: make(map[string]int)}
+	for name, endpoints := range groups {
+		if len(endpoints) == 0 {
+			return nil, fmt.Errorf("endpoint group %q is empty", name)
+		}
+		s.groups[name] = append([]string(nil), endpoints...)
+	}
+	if err := s.SetActive(active); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// WithSwitch makes Do send the request to the active group instead of the request URL host. Repeated
+// attempts (see Retry) go to the next endpoint of the group
+func WithSwitch(r *http.Request, s *Switch) *http.Request {
+	return withValue(r, keySwitch, s)
+}
+
+// SetActive switches new requests to the group, requests in flight are not affected, see Drain
+func (s *Switch) SetActive(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.groups[name]; !ok {
+		return fmt.Errorf("unknown endpoint group %q", name)
+	}
+	s.active = name
+	return nil
+}
+
+// Active returns the name of the active group
+func (s *Switch) Active() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+// Drain waits until requests in flight to the group are complete, including reading of response bodies
+func (s *Switch) Drain(ctx context.Context, name string) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		s.mu.Lock()
+		n := s.inflight[name]
+		s.mu.Unlock()
+		if n == 0 {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// switchTarget retargets the request to the active group of the Switch attached with WithSwitch, if any.
+// The request is counted as in flight to the group as soon as the group is picked, so Drain can not miss it,
+// the returned function is to be called with the response, the request is in flight until its body is closed
+func switchTarget(r *http.Request) (*http.Request, func(*http.Response), error) {
+	s, ok := r.Context().Value(keySwitch).(*Switch)
+	if !ok {
+		return r, func(*http.Response) {}, nil
+	}
+	attempt, _ := r.Context().Value(keyAttempt).(int)
+	if attempt < 1 {
+		attempt = 1
+	}
+	s.mu.Lock()
+	group := s.active
+	endpoints := s.groups[group]
+	s.inflight[group]++
+	s.mu.Unlock()
+
+	var once sync.Once
+	leave := func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.inflight[group]--
+		})
+	}
+	r, err := retarget(r, endpoints[(attempt-1)%len(endpoints)])
+	if err != nil {
+		leave()
+		return nil, nil, err
+	}
+	return r, func(resp *http.Response) { cancelOnClose(resp, leave) }, nil
+}
@@ -0,0 +1,101 @@
+package reqstrategy
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_Switch(t *testing.T) {
+	var mu sync.Mutex
+	var hosts []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		hosts = append(hosts, r.URL.Host)
+		mu.Unlock()
+		if r.URL.Host == "blue-1" {
+			return &http.Response{Request: r, StatusCode: 503}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 200, Body: ioutil.NopCloser(strings.NewReader("ok"))}, nil
+	})
+	s, err := NewSwitch("blue", map[string][]string{
+		"blue":  {"http://blue-1", "http://blue-2"},
+		"green": {"http://green-1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	req := WithStatusRequired(WithSwitch(newRequest(t), s), 200)
+	resp, err := Retry(client, req, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(hosts) != 2 || hosts[0] != "blue-1" || hosts[1] != "blue-2" {
+		t.Fatalf("expected retry to go to the next blue endpoint, got %v", hosts)
+	}
+
+	if err := s.SetActive("green"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := Do(client, req); err != nil || hosts[2] != "green-1" {
+		t.Fatalf("expected request to go to green, got %v: %v", hosts, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Drain(ctx, "blue"); err != context.DeadlineExceeded {
+		t.Fatalf("expected blue to have the request in flight until its body is closed, got %v", err)
+	}
+	resp.Body.Close()
+	if err := s.Drain(context.Background(), "blue"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := s.SetActive("purple"); err == nil {
+		t.Fatal("expected unknown group error")
+	}
+}
+
+func Test_Switch_Drain_pending(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{Request: r, StatusCode: 200}, nil
+	})
+	s, _ := NewSwitch("blue", map[string][]string{
+		"blue":  {"http://blue-1"},
+		"green": {"http://green-1"},
+	})
+
+	approving, approved := make(chan struct{}), make(chan struct{})
+	req := WithApproval(WithSwitch(newRequest(t), s), func(a Attempt) error {
+		close(approving)
+		<-approved
+		return nil
+	})
+	done := make(chan *http.Response)
+	go func() {
+		resp, _ := Do(client, req)
+		done <- resp
+	}()
+	<-approving
+	s.SetActive("green")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Drain(ctx, "blue"); err != context.DeadlineExceeded {
+		t.Fatalf("expected blue to have the request waiting for approval in flight, got %v", err)
+	}
+	close(approved)
+	resp := <-done
+	if resp == nil || resp.Request.URL.Host != "blue-1" {
+		t.Fatalf("expected the request sent to blue, got %v", resp)
+	}
+	resp.Body.Close()
+	if err := s.Drain(context.Background(), "blue"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
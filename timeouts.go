@@ -0,0 +1,110 @@
+package reqstrategy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrHeaderTimeout is returned when the response headers are not received in time, see WithHeaderTimeout
+	ErrHeaderTimeout = errors.New("response header timeout")
+	// ErrBodyTimeout is returned by the response body reads when the body is not read in time, see WithBodyTimeout
+	ErrBodyTimeout = errors.New("response body timeout")
+)
+
+const (
+	headerExpired int32 = iota + 1
+	bodyExpired
+)
+
+// WithHeaderTimeout limits the time every attempt waits for the response headers, the attempt
+// fails with ErrHeaderTimeout once it is over. It tells unresponsive servers apart, unlike the context
+// deadline covering the whole strategy
+func WithHeaderTimeout(r *http.Request, d time.Duration) *http.Request {
+	return withValue(r, keyHeaderTimeout, d)
+}
+
+// WithBodyTimeout limits the time to read the whole response body counting from the moment headers are
+// received, body reads fail with ErrBodyTimeout once it is over. It applies to every attempt and covers
+// validators reading the body as well
+func WithBodyTimeout(r *http.Request, d time.Duration) *http.Request {
+	return withValue(r, keyBodyTimeout, d)
+}
+
+// startTimeouts applies header and body timeouts of the request, it returns the request to send and
+// the function translating its outcome
+func startTimeouts(r *http.Request) (*http.Request, func(*http.Response, error) (*http.Response, error)) {
+	header, _ := r.Context().Value(keyHeaderTimeout).(time.Duration)
+	body, _ := r.Context().Value(keyBodyTimeout).(time.Duration)
+	if header <= 0 && body <= 0 {
+		return r, func(resp *http.Response, err error) (*http.Response, error) {
+			return resp, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	var expired int32
+	var timer *time.Timer
+	if header > 0 {
+		timer = time.AfterFunc(header, func() {
+			atomic.CompareAndSwapInt32(&expired, 0, headerExpired)
+			cancel()
+		})
+	}
+	return r.WithContext(ctx), func(resp *http.Response, err error) (*http.Response, error) {
+		if timer != nil {
+			timer.Stop()
+		}
+		if atomic.LoadInt32(&expired) == headerExpired {
+			closeBody(resp)
+			cancel()
+			return nil, ErrHeaderTimeout
+		}
+		if err != nil || resp.Body == nil {
+			cancel()
+			return resp, err
+		}
+		if body <= 0 {
+			cancelOnClose(resp, cancel)
+			return resp, nil
+		}
+		resp.Body = &timedBody{
+			ReadCloser: resp.Body,
+			expired:    &expired,
+			cancel:     cancel,
+			timer: time.AfterFunc(body, func() {
+				atomic.CompareAndSwapInt32(&expired, 0, bodyExpired)
+				cancel()
+			}),
+		}
+		return resp, nil
+	}
+}
+
+// timedBody reports reads failed because of the body timeout as ErrBodyTimeout
+type timedBody struct {
+	io.ReadCloser
+	expired *int32
+	timer   *time.Timer
+	cancel  context.CancelFunc
+}
+
+func (b *timedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.timer.Stop()
+	} else if err != nil && atomic.LoadInt32(b.expired) == bodyExpired {
+		err = ErrBodyTimeout
+	}
+	return n, err
+}
+
+func (b *timedBody) Close() error {
+	b.timer.Stop()
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
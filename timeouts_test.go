@@ -0,0 +1,64 @@
+package reqstrategy
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_WithHeaderTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	r, _ := http.NewRequest("GET", server.URL+"/slow", nil)
+	if _, err := Do(server.Client(), WithHeaderTimeout(r, 20*time.Millisecond)); err != ErrHeaderTimeout {
+		t.Fatalf("expected ErrHeaderTimeout, got %v", err)
+	}
+
+	r, _ = http.NewRequest("GET", server.URL+"/fast", nil)
+	resp, err := Do(server.Client(), WithHeaderTimeout(r, time.Second))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+	if body, err := ioutil.ReadAll(resp.Body); err != nil || string(body) != "ok" {
+		t.Fatalf("expected body to be readable, got %q %v", body, err)
+	}
+}
+
+func Test_WithBodyTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	r, _ := http.NewRequest("GET", server.URL, nil)
+	resp, err := Do(server.Client(), WithBodyTimeout(WithHeaderTimeout(r, time.Second), 20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("expected headers to arrive in time, got %s", err)
+	}
+	defer resp.Body.Close()
+	if _, err := ioutil.ReadAll(resp.Body); err != ErrBodyTimeout {
+		t.Fatalf("expected ErrBodyTimeout, got %v", err)
+	}
+
+	r, _ = http.NewRequest("GET", server.URL, nil)
+	if _, err := Do(server.Client(), WithCompleteBody(WithBodyTimeout(r, 20*time.Millisecond))); err == nil {
+		t.Fatal("expected validator reading the body to fail")
+	}
+}
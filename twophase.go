@@ -0,0 +1,89 @@
+package reqstrategy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNoQuorum is returned by TwoPhase when too few endpoints prepared the change
+var ErrNoQuorum = errors.New("quorum not reached")
+
+// TwoPhase coordinates the change across endpoints: prepare requests are sent to all endpoints
+// simultaneously and if at least quorum of them succeed, commit requests are sent to the endpoints
+// which prepared, otherwise they get abort requests and ErrNoQuorum is returned. Requests are paired
+// by index, abort may be <nil> for endpoints with nothing to abort. Commit responses are returned in
+// the same order with <nil> for endpoints which did not prepare or failed to commit, error is returned
+// if fewer than quorum commits succeeded, commit response bodies are readable until closed. Prepare and abort
+// response bodies are closed
+func TwoPhase(client *http.Client, quorum int, prepare, commit, abort []*http.Request) ([]*http.Response, error) {
+	if len(commit) != len(prepare) || (abort != nil && len(abort) != len(prepare)) {
+		return nil, errors.New("prepare, commit and abort requests do not match")
+	}
+	prepare = withStrategies(prepare, "TwoPhase")
+	commit = withStrategies(commit, "TwoPhase")
+	if abort != nil {
+		abort = withStrategies(abort, "TwoPhase")
+	}
+	if err := planRequests(prepare, 1); err != nil {
+		return nil, err
+	}
+
+	var prepared []int
+	for i, res := range runAll(client, prepare) {
+		closeBody(res.response)
+		if res.err == nil {
+			prepared = append(prepared, i)
+		}
+	}
+
+	if len(prepared) < quorum {
+		var aborts []*http.Request
+		for _, i := range prepared {
+			if abort != nil && abort[i] != nil {
+				aborts = append(aborts, abort[i])
+			}
+		}
+		for _, res := range runAll(client, aborts) {
+			closeBody(res.response)
+		}
+		return nil, ErrNoQuorum
+	}
+
+	commits := make([]*http.Request, len(prepared))
+	for j, i := range prepared {
+		commits[j] = commit[i]
+	}
+	var committed int
+	responses := make([]*http.Response, len(prepare))
+	for j, res := range runAll(client, commits) {
+		if res.err == nil {
+			committed++
+			responses[prepared[j]] = res.response
+		} else {
+			closeBody(res.response)
+		}
+	}
+	if committed < quorum {
+		return responses, fmt.Errorf("%d of %d commits succeeded, %d required", committed, len(commits), quorum)
+	}
+	return responses, nil
+}
+
+// runAll sends requests simultaneously waiting for all of them, results are returned in the order of requests.
+// Nothing is left to cancel, so every successful response keeps running until its body is closed
+func runAll(client *http.Client, requests []*http.Request) []result {
+	results := make([]result, len(requests))
+	pending := run(client, requests, nil)
+	for range requests {
+		res := <-pending
+		results[res.order] = res
+	}
+	return results
+}
+
+func closeBody(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,80 @@
+package reqstrategy
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func Test_TwoPhase(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		calls = append(calls, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/c/prepare" {
+			return &http.Response{StatusCode: 409, Request: r}, nil
+		}
+		return &http.Response{StatusCode: 200, Request: r}, nil
+	})
+	phase := func(name string) []*http.Request {
+		var requests []*http.Request
+		for _, endpoint := range []string{"a", "b", "c"} {
+			requests = append(requests, WithStatusRequired(newRequest(t, endpoint, name), 200))
+		}
+		return requests
+	}
+
+	responses, err := TwoPhase(client, 2, phase("prepare"), phase("commit"), phase("abort"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if responses[0] == nil || responses[1] == nil || responses[2] != nil {
+		t.Fatalf("expected commits for prepared endpoints only, got %v", responses)
+	}
+	sort.Strings(calls)
+	expected := []string{"/a/commit", "/a/prepare", "/b/commit", "/b/prepare", "/c/prepare"}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("expected calls %v, got %v", expected, calls)
+		}
+	}
+
+	calls = nil
+	_, err = TwoPhase(client, 3, phase("prepare"), phase("commit"), phase("abort"))
+	if err != ErrNoQuorum {
+		t.Fatalf("expected ErrNoQuorum, got %v", err)
+	}
+	sort.Strings(calls)
+	if len(calls) != 5 || calls[0] != "/a/abort" || calls[2] != "/b/abort" {
+		t.Fatalf("expected prepared endpoints to be aborted, got %v", calls)
+	}
+}
+
+func Test_TwoPhase_streamedCommit(t *testing.T) {
+	server := newStreamingServer()
+	defer server.Close()
+	post := func(path string) []*http.Request {
+		var requests []*http.Request
+		for i := 0; i < 2; i++ {
+			r, _ := http.NewRequest("POST", server.URL+path, nil)
+			requests = append(requests, WithStatusRequired(r, 200))
+		}
+		return requests
+	}
+
+	responses, err := TwoPhase(server.Client(), 2, post("/prepare"), post("/commit"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for i, resp := range responses {
+		if body, err := readBody(resp); err != nil || body != "/commit" {
+			t.Fatalf("expected commit response #%d readable, got %q, %v", i, body, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package reqstrategy
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StatusResumeIncomplete is the status servers supporting chunked uploads reply with to acknowledge
+// the chunk when more data is expected
+const StatusResumeIncomplete = 308
+
+// Upload sends the body produced by src in chunks of chunkSize bytes, each chunk as a separate request
+// made of r with Content-Range header set, e.g. "bytes 0-1023/*", the total size is sent with the last chunk.
+// Only the current chunk is kept in memory, so the stream does not have to be regenerated when a chunk fails:
+// it is retried after intervals starting from the offset acknowledged by the server. Intermediate chunks
+// are acknowledged with 308 Resume Incomplete or 2xx status, Range response header "bytes=0-N"
+// tells how much of the chunk was persisted, the whole chunk is assumed without the header.
+// Response to the last chunk is validated as usual and returned. Upload stops between chunks with *CancelError
+// once the request is cancelled, see CancelCause
+func Upload(client *http.Client, r *http.Request, src io.Reader, chunkSize int, intervals ...time.Duration) (*http.Response, error) {
+	r = withStrategy(r, "Upload")
+	reader := bufio.NewReaderSize(src, chunkSize)
+	chunk := make([]byte, chunkSize)
+	var offset int64
+	for step := 0; ; step++ {
+		if err := checkpoint(r, step); err != nil {
+			return nil, err
+		}
+		n, err := io.ReadFull(reader, chunk)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		_, err = reader.Peek(1)
+		last := err == io.EOF
+
+		data := chunk[:n]
+		resp, err := retry(r, intervals, func(attempt int) (*http.Response, error) {
+			resp, err := sendChunk(client, withAttempt(r, attempt), data, offset, last)
+			if err == nil && !last {
+				// server may persist just a part of the chunk
+				acked := acknowledged(resp, offset, offset+int64(len(data)))
+				if acked < offset+int64(len(data)) {
+					data, offset = data[acked-offset:], acked
+					return resp, fmt.Errorf("%s %s: chunk acknowledged up to %d bytes", r.Method, r.URL, acked)
+				}
+			}
+			return resp, err
+		})
+		if err != nil || last {
+			return resp, interrupted(r, step, err)
+		}
+		offset += int64(len(data))
+	}
+}
+
+func sendChunk(client *http.Client, r *http.Request, data []byte, offset int64, last bool) (*http.Response, error) {
+	total := "*"
+	if last {
+		total = strconv.FormatInt(offset+int64(len(data)), 10)
+	}
+	chunkRange := fmt.Sprintf("bytes %d-%d/%s", offset, offset+int64(len(data))-1, total)
+	if len(data) == 0 {
+		chunkRange = "bytes */" + total
+	}
+
+	c := withHeader(withBody(r, data), "Content-Range", chunkRange)
+	if !last {
+		c = withValue(c, keyValidators, []validator{func(resp *http.Response) error {
+			if resp.StatusCode == StatusResumeIncomplete || resp.StatusCode/100 == 2 {
+				return nil
+			}
+			return fmt.Errorf("%s %s: chunk %s rejected with status %d", c.Method, c.URL, chunkRange, resp.StatusCode)
+		}})
+	}
+	resp, err := Do(client, c)
+	if !last && resp != nil && resp.Body != nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+// acknowledged returns the offset within the chunk from start to end the server persisted the data up to,
+// end if the response does not say
+func acknowledged(resp *http.Response, start, end int64) int64 {
+	header := resp.Header.Get("Range")
+	if !strings.HasPrefix(header, "bytes=") {
+		return end
+	}
+	bounds := strings.SplitN(strings.TrimPrefix(header, "bytes="), "-", 2)
+	if len(bounds) != 2 {
+		return end
+	}
+	last, err := strconv.ParseInt(bounds[1], 10, 64)
+	if err != nil || last+1 > end {
+		return end
+	}
+	if last+1 < start {
+		return start
+	}
+	return last + 1
+}
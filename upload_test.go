@@ -0,0 +1,50 @@
+package reqstrategy
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Upload(t *testing.T) {
+	var stored bytes.Buffer
+	var ranges []string
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		chunk, _ := ioutil.ReadAll(r.Body)
+		ranges = append(ranges, r.Header.Get("Content-Range"))
+		switch len(ranges) {
+		case 2:
+			return nil, fmt.Errorf("connection reset")
+		case 3:
+			// only a part of the chunk made it
+			stored.Write(chunk[:2])
+			header := http.Header{"Range": {fmt.Sprintf("bytes=0-%d", stored.Len()-1)}}
+			return &http.Response{Request: r, StatusCode: StatusResumeIncomplete, Header: header}, nil
+		}
+		stored.Write(chunk)
+		if strings.HasSuffix(r.Header.Get("Content-Range"), "/*") {
+			return &http.Response{Request: r, StatusCode: StatusResumeIncomplete}, nil
+		}
+		return &http.Response{Request: r, StatusCode: 201}, nil
+	})
+
+	req, _ := http.NewRequest("PUT", "http://localhost/upload", nil)
+	resp, err := Upload(client, WithStatusRequired(req, 201), strings.NewReader("0123456789ab"), 5, time.Millisecond, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.StatusCode != 201 {
+		t.Fatalf("expected response status 201, got %d", resp.StatusCode)
+	}
+	if stored.String() != "0123456789ab" {
+		t.Fatalf(`expected "0123456789ab" to be stored, got "%s"`, stored.String())
+	}
+	want := "bytes 0-4/*, bytes 5-9/*, bytes 5-9/*, bytes 7-9/*, bytes 10-11/12"
+	if got := strings.Join(ranges, ", "); got != want {
+		t.Fatalf(`expected "%s" ranges, got "%s"`, want, got)
+	}
+}
@@ -0,0 +1,52 @@
+package reqstrategy
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// StepError is returned by Waterfall when a step failed
+type StepError struct {
+	// Step is the index of the failed step
+	Step int
+	// Name is the name of the failed step, if any
+	Name string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("step %d (%s) failed: %s", e.Step, e.Name, e.Err)
+	}
+	return fmt.Sprintf("step %d failed: %s", e.Step, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// Waterfall sends step requests one by one with Do, every step is limited by its own timeout and checked
+// with its own validator in addition to the request ones. The first failure stops the workflow with *StepError,
+// response bodies of the previous steps are closed. Responses of all steps are returned on success.
+// Step compensations are ignored, see Saga. Cancelled workflow fails with *StepError holding *CancelError
+func Waterfall(client *http.Client, steps []Step) ([]*http.Response, error) {
+	responses := make([]*http.Response, 0, len(steps))
+	for i, step := range steps {
+		err := checkpoint(step.Request, i)
+		var resp *http.Response
+		if err == nil {
+			resp, err = step.send(client, withChildID(withStrategy(step.Request, "Waterfall"), i+1))
+		}
+		if err == nil {
+			responses = append(responses, resp)
+			continue
+		}
+		err = interrupted(step.Request, i, err)
+		closeBody(resp)
+		for _, resp := range responses {
+			closeBody(resp)
+		}
+		return nil, &StepError{Step: i, Name: step.Name, Err: err}
+	}
+	return responses, nil
+}
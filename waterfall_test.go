@@ -0,0 +1,53 @@
+package reqstrategy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_Waterfall(t *testing.T) {
+	client := newClient(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+				return nil, r.Context().Err()
+			case <-time.After(time.Second):
+			}
+		}
+		return &http.Response{StatusCode: 200, Request: r, Body: http.NoBody}, nil
+	})
+	notEmpty := func(resp *http.Response) error {
+		if resp.ContentLength == 0 {
+			return errors.New("empty response")
+		}
+		return nil
+	}
+
+	responses, err := Waterfall(client, []Step{
+		{Request: newRequest(t, "login"), Name: "login"},
+		{Request: newRequest(t, "profile"), Timeout: time.Second},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(responses) != 2 || responses[1].Request.URL.Path != "/profile" {
+		t.Fatalf("expected responses of both steps, got %d", len(responses))
+	}
+
+	_, err = Waterfall(client, []Step{
+		{Request: newRequest(t, "login"), Name: "login"},
+		{Request: newRequest(t, "slow"), Name: "slow", Timeout: 10 * time.Millisecond},
+	})
+	stepErr, ok := err.(*StepError)
+	if !ok || stepErr.Step != 1 || stepErr.Name != "slow" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected slow step to time out, got %v", err)
+	}
+
+	_, err = Waterfall(client, []Step{{Request: newRequest(t, "login"), Validate: notEmpty}})
+	if err == nil || err.Error() != "step 0 failed: empty response" {
+		t.Fatalf("expected step validator to fail, got %v", err)
+	}
+}